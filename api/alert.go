@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// AlertQuery is part of AlertRule.Data
+type AlertQuery struct {
+	RefId             string `json:"refId"`
+	QueryType         string `json:"queryType"`
+	RelativeTimeRange struct {
+		From int `json:"from"`
+		To   int `json:"to"`
+	} `json:"relativeTimeRange"`
+	DataSourceUID string                 `json:"datasourceUid"`
+	Model         map[string]interface{} `json:"model"`
+}
+
+// AlertRule is grafana managed alert rule in /api/v1/provisioning/alert-rules
+type AlertRule struct {
+	Id                   int               `json:"id,omitempty"`
+	UID                  string            `json:"uid"`
+	OrgID                int               `json:"orgID"`
+	FolderUID            string            `json:"folderUID"`
+	RuleGroup            string            `json:"ruleGroup"`
+	Title                string            `json:"title"`
+	Condition            string            `json:"condition"`
+	Data                 []AlertQuery      `json:"data"`
+	Updated              string            `json:"updated,omitempty"`
+	NoDataState          string            `json:"noDataState"`
+	ExecErrState         string            `json:"execErrState"`
+	For                  string            `json:"for"`
+	Annotations          map[string]string `json:"annotations,omitempty"`
+	Labels               map[string]string `json:"labels,omitempty"`
+	IsPaused             bool              `json:"isPaused"`
+	NotificationSettings interface{}       `json:"notification_settings,omitempty"`
+	Provenance           string            `json:"provenance,omitempty"`
+}
+
+// RuleGroup is evaluation group of alert rules in folder.
+type RuleGroup struct {
+	Title     string      `json:"title"`
+	FolderUID string      `json:"folderUid"`
+	Interval  int         `json:"interval"` // seconds
+	Rules     []AlertRule `json:"rules"`
+}
+
+func GetAlertRules(grafana config.Grafana) ([]AlertRule, error) {
+	body, err := getBody(grafana, "/api/v1/provisioning/alert-rules")
+	if err != nil {
+		return nil, err
+	}
+	rules := []AlertRule{}
+	err = json.Unmarshal(body, &rules)
+	return rules, err
+}
+
+func ruleGroupPath(folderUID, group string) string {
+	return fmt.Sprintf("/api/v1/provisioning/folder/%s/rule-groups/%s", url.PathEscape(folderUID), url.PathEscape(group))
+}
+
+// GetRuleGroup returns rule group with its rules.
+func GetRuleGroup(grafana config.Grafana, folderUID, group string) (RuleGroup, error) {
+	body, err := getBody(grafana, ruleGroupPath(folderUID, group))
+	if err != nil {
+		return RuleGroup{}, err
+	}
+	ruleGroup := RuleGroup{}
+	err = json.Unmarshal(body, &ruleGroup)
+	return ruleGroup, err
+}
+
+// PutRuleGroup creates or replaces rule group. Rules missing from group are deleted from it.
+// Rules of caller are left as they are.
+func PutRuleGroup(grafana config.Grafana, group RuleGroup) error {
+	group.Rules = slices.Clone(group.Rules)
+	for idx := range group.Rules {
+		group.Rules[idx].Id = 0
+		group.Rules[idx].OrgID = 0
+		group.Rules[idx].Provenance = ""
+	}
+	_, err := putBody(grafana, ruleGroupPath(group.FolderUID, group.Title), group)
+	return err
+}
+
+// MapDataSources returns copy of rule, where data source uids in queries are replaced according to mapping.
+func (rule *AlertRule) MapDataSources(mapping map[string]string) (AlertRule, error) {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return *rule, err
+	}
+	mapped := AlertRule{}
+	if err = json.Unmarshal(data, &mapped); err != nil || len(mapping) == 0 {
+		return mapped, err
+	}
+	for idx, query := range mapped.Data {
+		if to, ok := mapping[query.DataSourceUID]; ok {
+			mapped.Data[idx].DataSourceUID = to
+		}
+		mapDataSourceUIDs(query.Model, mapping)
+	}
+	return mapped, nil
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+const alertRule = `{
+	"id": 3, "uid": "cpu-high", "orgID": 1, "folderUID": "infra", "ruleGroup": "kubernetes",
+	"title": "CPU high", "condition": "C", "for": "5m", "noDataState": "NoData", "execErrState": "Error",
+	"labels": {"severity": "critical"},
+	"data": [
+		{"refId": "A", "datasourceUid": "test-prometheus", "relativeTimeRange": {"from": 600, "to": 0},
+		 "model": {"expr": "avg(rate(cpu[5m]))", "datasource": {"type": "prometheus", "uid": "test-prometheus"}}},
+		{"refId": "C", "datasourceUid": "__expr__", "model": {"type": "threshold", "expression": "A"}}
+	]
+}`
+
+func TestAlertRuleMapDataSources(t *testing.T) {
+	rule := AlertRule{}
+	if err := json.Unmarshal([]byte(alertRule), &rule); err != nil {
+		t.Fatalf("Unmarshal failed due to %v", err)
+	}
+	mapped, err := rule.MapDataSources(map[string]string{"test-prometheus": "prod-prometheus"})
+	if err != nil {
+		t.Fatalf("MapDataSources failed due to %v", err)
+	}
+	if mapped.Data[0].DataSourceUID != "prod-prometheus" || mapped.Data[1].DataSourceUID != "__expr__" {
+		t.Errorf("wrong data source uids: %#v", mapped.Data)
+	}
+	if uid := mapped.Data[0].Model["datasource"].(map[string]interface{})["uid"]; uid != "prod-prometheus" {
+		t.Errorf("model data source was not mapped (%s)", uid)
+	}
+	if rule.Data[0].DataSourceUID != "test-prometheus" {
+		t.Errorf("original rule was modified")
+	}
+}
+
+func TestPutRuleGroupKeepsRules(t *testing.T) {
+	body := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	rule := AlertRule{}
+	if err := json.Unmarshal([]byte(alertRule), &rule); err != nil {
+		t.Fatalf("unable to parse rule: %v", err)
+	}
+	rule.Provenance = "api"
+	group := RuleGroup{Title: "kubernetes", FolderUID: "infra", Rules: []AlertRule{rule}}
+	if err := PutRuleGroup(config.Grafana{Name: "test", URL: server.URL}, group); err != nil {
+		t.Fatalf("PutRuleGroup failed due to %v", err)
+	}
+	if strings.Contains(body, `"orgID":1`) || strings.Contains(body, `"provenance":"api"`) {
+		t.Errorf("server specific fields were sent: %s", body)
+	}
+	if group.Rules[0].Id != 3 || group.Rules[0].OrgID != 1 || group.Rules[0].Provenance != "api" {
+		t.Errorf("PutRuleGroup modified rules of caller: %#v", group.Rules[0])
+	}
+}
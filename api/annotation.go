@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// Annotation is item in /api/annotations. Time and TimeEnd are epoch milliseconds.
+// Organization annotations don't have dashboard.
+type Annotation struct {
+	Id           int      `json:"id,omitempty"`
+	DashboardId  int      `json:"dashboardId,omitempty"`
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelId      int      `json:"panelId,omitempty"`
+	Time         int64    `json:"time"`
+	TimeEnd      int64    `json:"timeEnd,omitempty"`
+	Text         string   `json:"text"`
+	Tags         []string `json:"tags"`
+}
+
+// AnnotationFilter limits annotations by time range and tags.
+type AnnotationFilter struct {
+	From  time.Time
+	To    time.Time
+	Tags  []string
+	Limit int
+}
+
+func GetAnnotations(grafana config.Grafana, filter AnnotationFilter) ([]Annotation, error) {
+	query := url.Values{"type": {"annotation"}}
+	if !filter.From.IsZero() {
+		query.Set("from", strconv.FormatInt(filter.From.UnixMilli(), 10))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", strconv.FormatInt(filter.To.UnixMilli(), 10))
+	}
+	for _, tag := range filter.Tags {
+		query.Add("tags", tag)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	return getList[Annotation](grafana, fmt.Sprintf("/api/annotations?%s", query.Encode()))
+}
+
+func CreateAnnotation(grafana config.Grafana, annotation Annotation) error {
+	annotation.Id = 0
+	annotation.DashboardId = 0
+	_, err := postBody(grafana, "/api/annotations", annotation)
+	return err
+}
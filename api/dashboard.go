@@ -1,9 +1,11 @@
 package api
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"reflect"
 
 	"github.com/jylitalo/grafana-dashboard-sync/config"
 )
@@ -13,23 +15,27 @@ import (
 //	{
 //		"id":3,"uid":"c0be4e42-43fc-4f37-8e5f-f7d70f58284e","title":"App Debug",
 //		"uri":"db/app-debug","url":"/d/c0be4e42-43fc-4f37-8e5f-f7d70f58284e/app-debug",
-//		"slug":"","type":"dash-db","tags":[],"isStarred":false,"sortMeta":0
+//		"slug":"","type":"dash-db","tags":[],"isStarred":false,"sortMeta":0,
+//		"folderId":5,"folderUid":"b4e1f3c6-2a1d-4c8e-9d7a-0f5c2e6b8a91","folderTitle":"Kubernetes"
 //	},
 //	...
 //
 // ]
 type Dashboard struct {
-	Id        int      `json:"id"`
-	UID       string   `json:"uid"`
-	Title     string   `json:"title"`
-	URI       string   `json:"uri"`
-	URL       string   `json:"url"`
-	Slug      string   `json:"slug"`
-	Type      string   `json:"type"`
-	Tags      []string `json:"tags"`
-	IsStarred bool     `json:"isStarred"`
-	SortMeta  int      `json:"sortMeta"`
-	grafana   config.Grafana
+	Id          int      `json:"id"`
+	UID         string   `json:"uid"`
+	Title       string   `json:"title"`
+	URI         string   `json:"uri"`
+	URL         string   `json:"url"`
+	Slug        string   `json:"slug"`
+	Type        string   `json:"type"`
+	Tags        []string `json:"tags"`
+	IsStarred   bool     `json:"isStarred"`
+	SortMeta    int      `json:"sortMeta"`
+	FolderId    int      `json:"folderId,omitempty"`
+	FolderUID   string   `json:"folderUid,omitempty"`
+	FolderTitle string   `json:"folderTitle,omitempty"`
+	grafana     config.Grafana
 }
 
 // AnnotationsPermissions is part of DashboardJSON.Meta
@@ -125,6 +131,163 @@ type DashboardJSON struct {
 		Version    int         `json:"version"`
 		WeekStart  string      `json:"weekStart"`
 	} `json:"dashboard"`
+	// raw is dashboard content as it was read. Fields, which aren't modelled above, are kept from it.
+	raw json.RawMessage
+	// body is response, which dashboard was parsed from.
+	body json.RawMessage
+}
+
+// plainDashboardJSON is DashboardJSON without its own (un)marshalling.
+type plainDashboardJSON DashboardJSON
+
+// UnmarshalJSON fills modelled fields and keeps raw content of dashboard.
+func (dashboard *DashboardJSON) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*plainDashboardJSON)(dashboard)); err != nil {
+		return err
+	}
+	wrapper := struct {
+		Dashboard json.RawMessage `json:"dashboard"`
+	}{}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return err
+	}
+	dashboard.raw = nil
+	if len(wrapper.Dashboard) > 0 && string(wrapper.Dashboard) != "null" {
+		dashboard.raw = wrapper.Dashboard
+	}
+	return nil
+}
+
+// MarshalJSON writes meta and full content of dashboard.
+func (dashboard DashboardJSON) MarshalJSON() ([]byte, error) {
+	content, err := dashboard.Content()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Meta      interface{}            `json:"meta"`
+		Dashboard map[string]interface{} `json:"dashboard"`
+	}{dashboard.Meta, content})
+}
+
+// generic converts value into maps, lists and scalars via JSON.
+func generic(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// itemKey identifies list item by its id, refId or name.
+func itemKey(item interface{}) string {
+	if m, ok := item.(map[string]interface{}); ok {
+		for _, key := range []string{"id", "refId", "name"} {
+			if value, ok := m[key]; ok && value != nil {
+				return key + "=" + fmt.Sprint(value)
+			}
+		}
+	}
+	return ""
+}
+
+// overlay returns value with fields from raw, which aren't modelled. Known is modelled part of raw,
+// so parts where value equals to known are taken from raw as they are.
+// Items of lists are paired by itemKey, or by index when items don't have keys.
+func overlay(raw, known, value interface{}) interface{} {
+	if reflect.DeepEqual(known, value) {
+		return raw
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rawMap, ok := raw.(map[string]interface{})
+		knownMap, _ := known.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		ret := map[string]interface{}{}
+		for key, item := range v {
+			rawItem, found := rawMap[key]
+			switch {
+			case found:
+				ret[key] = overlay(rawItem, knownMap[key], item)
+			case !reflect.DeepEqual(knownMap[key], item):
+				ret[key] = item
+			}
+		}
+		for key, item := range rawMap {
+			_, inValue := v[key]
+			_, isKnown := knownMap[key]
+			if !inValue && !isKnown {
+				ret[key] = item
+			}
+		}
+		return ret
+	case []interface{}:
+		rawList, ok := raw.([]interface{})
+		knownList, _ := known.([]interface{})
+		if !ok || len(rawList) != len(knownList) {
+			return value
+		}
+		index := map[string]int{}
+		for idx := len(knownList) - 1; idx >= 0; idx-- {
+			if key := itemKey(knownList[idx]); key != "" {
+				index[key] = idx
+			}
+		}
+		ret := make([]interface{}, len(v))
+		for idx, item := range v {
+			ret[idx] = item
+			key := itemKey(item)
+			if pos, ok := index[key]; ok && key != "" {
+				ret[idx] = overlay(rawList[pos], knownList[pos], item)
+			} else if key == "" && len(v) == len(knownList) {
+				ret[idx] = overlay(rawList[idx], knownList[idx], item)
+			}
+		}
+		return ret
+	}
+	return value
+}
+
+// Content returns dashboard as generic JSON. Changes made into modelled fields are
+// applied on top of raw content, so fields which aren't modelled are kept.
+func (dashboard *DashboardJSON) Content() (map[string]interface{}, error) {
+	value, err := generic(dashboard.Dashboard)
+	if err != nil || dashboard.raw == nil {
+		content, _ := value.(map[string]interface{})
+		return content, err
+	}
+	var raw interface{}
+	if err = json.Unmarshal(dashboard.raw, &raw); err != nil {
+		return nil, err
+	}
+	parsed := DashboardJSON{}
+	if err = json.Unmarshal(dashboard.raw, &parsed.Dashboard); err != nil {
+		return nil, err
+	}
+	known, err := generic(parsed.Dashboard)
+	if err != nil {
+		return nil, err
+	}
+	content, ok := overlay(raw, known, value).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dashboard %s is not JSON object", dashboard.Dashboard.UID)
+	}
+	return content, nil
+}
+
+// WithContent returns copy of dashboard with given content.
+func (dashboard *DashboardJSON) WithContent(content map[string]interface{}) (DashboardJSON, error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return *dashboard, err
+	}
+	ret := DashboardJSON{Meta: dashboard.Meta, raw: data}
+	err = json.Unmarshal(data, &ret.Dashboard)
+	return ret, err
 }
 
 func GetDashboards(grafana config.Grafana) ([]Dashboard, error) {
@@ -143,6 +306,20 @@ func GetDashboards(grafana config.Grafana) ([]Dashboard, error) {
 	return sources, nil
 }
 
+// FindDashboard returns dashboard, which title or uid matches to name.
+func FindDashboard(grafana config.Grafana, name string) (Dashboard, error) {
+	dashdbs, err := GetDashboards(grafana)
+	if err != nil {
+		return Dashboard{}, err
+	}
+	for _, item := range dashdbs {
+		if item.Title == name || item.UID == name {
+			return item, nil
+		}
+	}
+	return Dashboard{}, fmt.Errorf("dashboard (%s) not found from %s", name, grafana.Name)
+}
+
 func (board *Dashboard) GetJSON() (DashboardJSON, error) {
 	path := fmt.Sprintf("/api/dashboards/uid/%s", board.UID)
 	body, err := getBody(board.grafana, path)
@@ -155,15 +332,31 @@ func (board *Dashboard) GetJSON() (DashboardJSON, error) {
 	return parseDashboardJSON(body)
 }
 
+// Delete removes dashboard from grafana.
+func (board *Dashboard) Delete() error {
+	_, err := deleteBody(board.grafana, fmt.Sprintf("/api/dashboards/uid/%s", board.UID))
+	return err
+}
+
 func parseDashboardJSON(body []byte) (DashboardJSON, error) {
 	source := DashboardJSON{}
 	err := json.Unmarshal(body, &source)
 	if err != nil {
 		slog.Error("parseDashboardJSON", "body", string(body), "err", err)
 	}
+	source.body = body
 	return source, err
 }
 
+// Body returns response from Grafana as it was received.
+// Dashboards, which weren't fetched from Grafana, are marshalled.
+func (dashboard *DashboardJSON) Body() (json.RawMessage, error) {
+	if dashboard.body != nil {
+		return dashboard.body, nil
+	}
+	return json.Marshal(dashboard)
+}
+
 func (dashboard *DashboardJSON) Flatten() []Panel {
 	flat := []Panel{}
 	for _, item := range dashboard.Dashboard.Panels {
@@ -171,3 +364,77 @@ func (dashboard *DashboardJSON) Flatten() []Panel {
 	}
 	return flat
 }
+
+// Hash returns checksum of dashboard content, including fields which aren't modelled.
+// Server specific fields (id and version) are left out from it.
+func (dashboard *DashboardJSON) Hash() (string, error) {
+	content, err := dashboard.Content()
+	if err != nil {
+		return "", err
+	}
+	delete(content, "id")
+	delete(content, "version")
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// PutDashboard creates or overwrites dashboard in grafana.
+// It returns version number that grafana gave to saved dashboard.
+func PutDashboard(grafana config.Grafana, dashboard DashboardJSON, message string) (int, error) {
+	content, err := dashboard.Content()
+	if err != nil {
+		return 0, err
+	}
+	delete(content, "id")
+	payload := map[string]interface{}{
+		"dashboard": content,
+		"folderUid": dashboard.Meta.FolderUID,
+		"message":   message,
+		"overwrite": true,
+	}
+	body, err := postBody(grafana, "/api/dashboards/db", payload)
+	if err != nil {
+		return 0, err
+	}
+	resp := struct {
+		Version int `json:"version"`
+	}{}
+	err = json.Unmarshal(body, &resp)
+	return resp.Version, err
+}
+
+func mapDataSourceUIDs(value interface{}, mapping map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if ds, ok := item.(map[string]interface{}); ok && key == "datasource" {
+				if uid, ok := ds["uid"].(string); ok {
+					if to, ok := mapping[uid]; ok {
+						ds["uid"] = to
+					}
+				}
+			}
+			mapDataSourceUIDs(item, mapping)
+		}
+	case []interface{}:
+		for _, item := range v {
+			mapDataSourceUIDs(item, mapping)
+		}
+	}
+}
+
+// MapDataSources returns copy of dashboard, where data source uids are replaced according to mapping.
+func (dashboard *DashboardJSON) MapDataSources(mapping map[string]string) (DashboardJSON, error) {
+	if len(mapping) == 0 {
+		return *dashboard, nil
+	}
+	content, err := dashboard.Content()
+	if err != nil {
+		return *dashboard, err
+	}
+	mapDataSourceUIDs(content, mapping)
+	return dashboard.WithContent(content)
+}
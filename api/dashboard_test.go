@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
 )
 
 var flatPanelList = []Panel{
@@ -97,6 +100,65 @@ func TestParseDashboardJSON(t *testing.T) {
 			if string(inJSON) != item {
 				t.Errorf("Not match ... %s", inJSON)
 			}
+			if body, err := inStruct.Body(); err != nil || string(body) != item {
+				t.Errorf("Body returned %s (%v)", body, err)
+			}
 		})
 	}
 }
+
+func TestMapDataSources(t *testing.T) {
+	original, err := parseDashboardJSON([]byte(appDebug))
+	if err != nil {
+		t.Fatalf("parseDashboardJSON failed due to %v", err)
+	}
+	mapped, err := original.MapDataSources(map[string]string{"f5976bf5-7c7a-4606-b2f5-311e2c9a02d9": "prod-prometheus"})
+	if err != nil {
+		t.Fatalf("MapDataSources failed due to %v", err)
+	}
+	if uid := mapped.Dashboard.Panels[0].Targets[0].DataSource.UID; uid != "prod-prometheus" {
+		t.Errorf("target data source was not mapped (%s)", uid)
+	}
+	if uid := original.Dashboard.Panels[0].Targets[0].DataSource.UID; uid != "f5976bf5-7c7a-4606-b2f5-311e2c9a02d9" {
+		t.Errorf("original dashboard was modified (%s)", uid)
+	}
+	inJSON, err := json.Marshal(mapped)
+	if err != nil {
+		t.Errorf("Marshal failed due to %v", err)
+	}
+	if strings.Contains(string(inJSON), "f5976bf5-7c7a-4606-b2f5-311e2c9a02d9") {
+		t.Errorf("unmapped data source uid left in %s", inJSON)
+	}
+}
+
+func TestUnmodelledFields(t *testing.T) {
+	body := `{"meta":{},"dashboard":{"uid":"x","title":"X","version":3,"customField":"one",` +
+		`"panels":[{"id":1,"title":"CPU","maxPerRow":4,"targets":[{"refId":"A","hide":false}]}]}}`
+	one, err1 := parseDashboardJSON([]byte(body))
+	two, err2 := parseDashboardJSON([]byte(strings.Replace(body, `"one"`, `"two"`, 1)))
+	if err := errors.Join(err1, err2); err != nil {
+		t.Fatalf("parseDashboardJSON failed due to %v", err)
+	}
+	hash1, err1 := one.Hash()
+	hash2, err2 := two.Hash()
+	if err := errors.Join(err1, err2); err != nil || hash1 == hash2 {
+		t.Errorf("unmodelled field didn't change hash (%s, %v)", hash1, err)
+	}
+	renamed := one
+	renamed.Dashboard.Panels = []Panel{one.Dashboard.Panels[0]}
+	renamed.Dashboard.Panels[0].Title = "Load"
+	transformed, err := renamed.Transform([]config.Rule{{Path: "title", Set: "Y"}})
+	if err != nil {
+		t.Fatalf("Transform failed due to %v", err)
+	}
+	content, err := transformed.Content()
+	if err != nil {
+		t.Fatalf("content failed due to %v", err)
+	}
+	data, _ := json.Marshal(content)
+	for _, part := range []string{`"customField":"one"`, `"maxPerRow":4`, `"title":"Load"`, `"title":"Y"`} {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("%s missing from %s", part, data)
+		}
+	}
+}
@@ -26,9 +26,24 @@ import (
 //
 // ]
 type DataSource struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
-	UID  string `json:"uid"`
+	Id               int                    `json:"id"`
+	UID              string                 `json:"uid"`
+	OrgId            int                    `json:"orgId"`
+	Name             string                 `json:"name"`
+	Type             string                 `json:"type"`
+	TypeName         string                 `json:"typeName,omitempty"`
+	Access           string                 `json:"access"`
+	URL              string                 `json:"url"`
+	User             string                 `json:"user"`
+	Database         string                 `json:"database"`
+	BasicAuth        bool                   `json:"basicAuth"`
+	BasicAuthUser    string                 `json:"basicAuthUser,omitempty"`
+	WithCredentials  bool                   `json:"withCredentials"`
+	IsDefault        bool                   `json:"isDefault"`
+	JSONData         map[string]interface{} `json:"jsonData,omitempty"`
+	SecureJSONFields map[string]bool        `json:"secureJsonFields,omitempty"`
+	Version          int                    `json:"version,omitempty"`
+	ReadOnly         bool                   `json:"readOnly"`
 }
 
 func GetDataSources(target config.Grafana) ([]DataSource, error) {
@@ -40,3 +55,132 @@ func GetDataSources(target config.Grafana) ([]DataSource, error) {
 	err = json.Unmarshal(body, &sources)
 	return sources, err
 }
+
+// GetDataSource returns full data source model, including secureJsonFields,
+// which are missing from GetDataSources.
+func GetDataSource(target config.Grafana, uid string) (DataSource, error) {
+	body, err := getBody(target, "/api/datasources/uid/"+uid)
+	if err != nil {
+		return DataSource{}, err
+	}
+	source := DataSource{}
+	err = json.Unmarshal(body, &source)
+	return source, err
+}
+
+// dataSourcePayload is body for creating and updating data source.
+// Grafana never returns secureJsonData, so it has to be given separately.
+type dataSourcePayload struct {
+	DataSource
+	SecureJSONData map[string]string `json:"secureJsonData,omitempty"`
+}
+
+// CreateDataSource adds new data source to grafana.
+func CreateDataSource(target config.Grafana, ds DataSource, secure map[string]string) error {
+	ds.Id = 0
+	ds.Version = 0
+	_, err := postBody(target, "/api/datasources", dataSourcePayload{DataSource: ds, SecureJSONData: secure})
+	return err
+}
+
+// UpdateDataSource overwrites existing data source in grafana.
+// Data source is identified by its uid.
+func UpdateDataSource(target config.Grafana, ds DataSource, secure map[string]string) error {
+	path := "/api/datasources/uid/" + ds.UID
+	_, err := putBody(target, path, dataSourcePayload{DataSource: ds, SecureJSONData: secure})
+	return err
+}
+
+// DataSourceRef is reference from dashboard to data source. Ref is uid or, in old
+// string form, name of data source. For references through variable, like ${DS_PROMETHEUS},
+// Variable is set and Ref is current value of variable (empty, if variable isn't defined).
+type DataSourceRef struct {
+	Ref      string
+	Type     string
+	Variable string
+	Location string
+}
+
+// builtinDataSources aren't real data sources, so references to them are left out.
+var builtinDataSources = map[string]bool{
+	"grafana": true, "-- Grafana --": true, "-- Mixed --": true, "-- Dashboard --": true, "__expr__": true,
+}
+
+// IsBuiltinDataSource tells whether uid or name is special data source provided by Grafana.
+func IsBuiltinDataSource(ref string) bool {
+	return builtinDataSources[ref]
+}
+
+// currentValue returns selected value of variable. First value is used from multi-value variables.
+func currentValue(variable Variable) string {
+	current, _ := variable.Current.(map[string]interface{})
+	switch value := current["value"].(type) {
+	case string:
+		return value
+	case []interface{}:
+		if len(value) > 0 {
+			first, _ := value[0].(string)
+			return first
+		}
+	}
+	return ""
+}
+
+// DataSourceRefs returns references to data sources from variables, panels and their targets.
+// Panel with queries, but without data source, refers to default data source. Targets without
+// data source use the one of their panel. Other empty and builtin references are left out.
+func (dashboard *DashboardJSON) DataSourceRefs() []DataSourceRef {
+	variables := varsToMap(dashboard.Dashboard.Templating.List)
+	refs := []DataSourceRef{}
+	add := func(location string, ds interface{}) {
+		uid := dataSourceUID(ds)
+		if uid == "" || builtinDataSources[uid] {
+			return
+		}
+		ref := DataSourceRef{Ref: uid, Location: location}
+		switch item := ds.(type) {
+		case map[string]interface{}:
+			ref.Type, _ = item["type"].(string)
+		case DashDataSource:
+			ref.Type = item.Type
+		}
+		if names := ParseVariableRefs(uid); len(names) == 1 {
+			ref.Variable = names[0]
+			ref.Ref = ""
+			if variable, ok := variables[names[0]]; ok {
+				ref.Ref = currentValue(variable)
+				if query, ok := variable.Query.(string); ok && ref.Type == "" && variable.Type == "datasource" {
+					ref.Type = query
+				}
+			}
+		}
+		refs = append(refs, ref)
+	}
+	for _, variable := range dashboard.Dashboard.Templating.List {
+		add("variable "+variable.Name, variable.DataSource)
+	}
+	for _, panel := range dashboard.Flatten() {
+		location := "panel " + panel.Title
+		panelDS := panel.DataSource
+		if dataSourceUID(panelDS) == "" && len(panel.Targets) > 0 {
+			panelDS = "default"
+		}
+		add(location+" datasource", panelDS)
+		for _, target := range panel.Targets {
+			var ds interface{} = target.DataSource
+			if target.DataSource.UID == "" {
+				ds = panelDS
+			}
+			add(location+" target "+target.RefId, ds)
+		}
+	}
+	return refs
+}
+
+func varsToMap(vars []Variable) map[string]Variable {
+	m := map[string]Variable{}
+	for _, item := range vars {
+		m[item.Name] = item
+	}
+	return m
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// Folder is item in /api/folders
+//
+//	{"id":5,"uid":"b4e1f3c6-2a1d-4c8e-9d7a-0f5c2e6b8a91","title":"Kubernetes"}
+type Folder struct {
+	Id        int    `json:"id"`
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	ParentUID string `json:"parentUid,omitempty"`
+}
+
+// GetFolders returns folders of grafana. Nested folders are returned only, when
+// grafana has nested folders enabled.
+func GetFolders(grafana config.Grafana) ([]Folder, error) {
+	body, err := getBody(grafana, "/api/folders?limit=1000")
+	if err != nil {
+		return nil, err
+	}
+	folders := []Folder{}
+	err = json.Unmarshal(body, &folders)
+	return folders, err
+}
+
+// CreateFolder creates folder with given uid and title.
+func CreateFolder(grafana config.Grafana, folder Folder) (Folder, error) {
+	payload := map[string]string{"uid": folder.UID, "title": folder.Title}
+	if folder.ParentUID != "" {
+		payload["parentUid"] = folder.ParentUID
+	}
+	body, err := postBody(grafana, "/api/folders", payload)
+	if err != nil {
+		return Folder{}, err
+	}
+	created := Folder{}
+	err = json.Unmarshal(body, &created)
+	return created, err
+}
@@ -1,30 +1,101 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/jylitalo/grafana-dashboard-sync/config"
 )
 
 func getBody(target config.Grafana, path string) ([]byte, error) {
+	return doRequest(target, http.MethodGet, path, nil)
+}
+
+func postBody(target config.Grafana, path string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return doRequest(target, http.MethodPost, path, data)
+}
+
+func putBody(target config.Grafana, path string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return doRequest(target, http.MethodPut, path, data)
+}
+
+func patchBody(target config.Grafana, path string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return doRequest(target, http.MethodPatch, path, data)
+}
+
+func deleteBody(target config.Grafana, path string) ([]byte, error) {
+	return doRequest(target, http.MethodDelete, path, nil)
+}
+
+func doRequest(target config.Grafana, method, path string, payload []byte) ([]byte, error) {
 	bearer := "Bearer " + target.Bearer
 	url := target.URL + path
-	req, err := http.NewRequest("GET", url, nil)
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, url, reader)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Authorization", bearer)
 	req.Header.Add("Content-Type", "application/json")
+	if strings.HasPrefix(path, "/api/v1/provisioning/") {
+		// keep provisioned resources editable in grafana UI
+		req.Header.Add("X-Disable-Provenance", "true")
+	}
 	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		slog.Debug("api request failed", "server", target.Name, "method", method, "path", path, "err", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
-	slog.Debug("api.getBody", "body", body, "err", err)
+	slog.Debug("api request", "server", target.Name, "method", method, "path", path,
+		"status", resp.StatusCode, "duration", time.Since(start), "bytes", len(body))
+	if err == nil && resp.StatusCode >= 300 {
+		err = &StatusError{Method: method, Path: path, Status: resp.Status, Code: resp.StatusCode, Body: body}
+	}
 	return body, err
 }
+
+// StatusError is returned, when grafana responds with error status.
+type StatusError struct {
+	Method string
+	Path   string
+	Status string
+	Code   int
+	Body   []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s failed with %s: %s", e.Method, e.Path, e.Status, e.Body)
+}
+
+// IsNotFound tells if err is caused by missing resource.
+func IsNotFound(err error) bool {
+	statusErr := &StatusError{}
+	return errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// Team is item in /api/teams/search
+type Team struct {
+	Id          int    `json:"id"`
+	UID         string `json:"uid,omitempty"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	MemberCount int    `json:"memberCount"`
+}
+
+// OrgUser is item in /api/org/users
+type OrgUser struct {
+	UserId int    `json:"userId"`
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+}
+
+func GetTeams(grafana config.Grafana) ([]Team, error) {
+	body, err := getBody(grafana, "/api/teams/search?perpage=1000")
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		Teams []Team `json:"teams"`
+	}{}
+	err = json.Unmarshal(body, &resp)
+	return resp.Teams, err
+}
+
+func GetOrgUsers(grafana config.Grafana) ([]OrgUser, error) {
+	body, err := getBody(grafana, "/api/org/users")
+	if err != nil {
+		return nil, err
+	}
+	users := []OrgUser{}
+	err = json.Unmarshal(body, &users)
+	return users, err
+}
+
+// TeamMember is item in /api/teams/:id/members
+type TeamMember struct {
+	UserId     int    `json:"userId"`
+	Login      string `json:"login"`
+	Email      string `json:"email"`
+	Permission int    `json:"permission"` // 0 member, 4 admin
+}
+
+// ServiceAccount is item in /api/serviceaccounts/search
+type ServiceAccount struct {
+	Id         int    `json:"id"`
+	Name       string `json:"name"`
+	Login      string `json:"login"`
+	Role       string `json:"role"`
+	IsDisabled bool   `json:"isDisabled"`
+	Tokens     int    `json:"tokens"`
+}
+
+func GetTeamMembers(grafana config.Grafana, teamId int) ([]TeamMember, error) {
+	body, err := getBody(grafana, fmt.Sprintf("/api/teams/%d/members", teamId))
+	if err != nil {
+		return nil, err
+	}
+	members := []TeamMember{}
+	err = json.Unmarshal(body, &members)
+	return members, err
+}
+
+func GetServiceAccounts(grafana config.Grafana) ([]ServiceAccount, error) {
+	body, err := getBody(grafana, "/api/serviceaccounts/search?perpage=1000")
+	if err != nil {
+		return nil, err
+	}
+	resp := struct {
+		ServiceAccounts []ServiceAccount `json:"serviceAccounts"`
+	}{}
+	err = json.Unmarshal(body, &resp)
+	return resp.ServiceAccounts, err
+}
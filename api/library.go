@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+const libraryPanelKind = 1
+
+// LibraryPanel is library element of panel kind in /api/library-elements.
+// Model is panel JSON, which dashboards refer to with Panel.LibraryPanel.
+type LibraryPanel struct {
+	Id          int                    `json:"id,omitempty"`
+	UID         string                 `json:"uid"`
+	Name        string                 `json:"name"`
+	Kind        int                    `json:"kind"`
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	FolderUID   string                 `json:"folderUid"`
+	Model       map[string]interface{} `json:"model"`
+	Version     int                    `json:"version"`
+}
+
+func GetLibraryPanels(grafana config.Grafana) ([]LibraryPanel, error) {
+	panels := []LibraryPanel{}
+	for page := 1; ; page++ {
+		body, err := getBody(grafana, fmt.Sprintf("/api/library-elements?kind=%d&perPage=100&page=%d", libraryPanelKind, page))
+		if err != nil {
+			return nil, err
+		}
+		resp := struct {
+			Result struct {
+				TotalCount int            `json:"totalCount"`
+				Elements   []LibraryPanel `json:"elements"`
+			} `json:"result"`
+		}{}
+		if err = json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		panels = append(panels, resp.Result.Elements...)
+		if len(resp.Result.Elements) == 0 || len(panels) >= resp.Result.TotalCount {
+			return panels, nil
+		}
+	}
+}
+
+// GetLibraryPanel returns library panel by uid. Use IsNotFound to check missing panel.
+func GetLibraryPanel(grafana config.Grafana, uid string) (LibraryPanel, error) {
+	body, err := getBody(grafana, "/api/library-elements/"+url.PathEscape(uid))
+	if err != nil {
+		return LibraryPanel{}, err
+	}
+	resp := struct {
+		Result LibraryPanel `json:"result"`
+	}{}
+	err = json.Unmarshal(body, &resp)
+	return resp.Result, err
+}
+
+// PutLibraryPanel creates library panel or, when version is given, updates existing one.
+func PutLibraryPanel(grafana config.Grafana, panel LibraryPanel) error {
+	payload := map[string]interface{}{
+		"uid":       panel.UID,
+		"name":      panel.Name,
+		"kind":      libraryPanelKind,
+		"folderUid": panel.FolderUID,
+		"model":     panel.Model,
+	}
+	if panel.Version == 0 {
+		_, err := postBody(grafana, "/api/library-elements", payload)
+		return err
+	}
+	payload["version"] = panel.Version
+	_, err := patchBody(grafana, "/api/library-elements/"+url.PathEscape(panel.UID), payload)
+	return err
+}
+
+// MapDataSources returns copy of library panel, where data source uids are replaced according to mapping.
+func (panel *LibraryPanel) MapDataSources(mapping map[string]string) (LibraryPanel, error) {
+	data, err := json.Marshal(panel)
+	if err != nil {
+		return *panel, err
+	}
+	mapped := LibraryPanel{}
+	if err = json.Unmarshal(data, &mapped); err != nil {
+		return mapped, err
+	}
+	mapDataSourceUIDs(mapped.Model, mapping)
+	return mapped, nil
+}
+
+// LibraryPanelUIDs returns uids of library panels used in dashboard.
+func (dashboard *DashboardJSON) LibraryPanelUIDs() []string {
+	uids := []string{}
+	seen := map[string]bool{}
+	for _, panel := range dashboard.Flatten() {
+		if panel.LibraryPanel != nil && !seen[panel.LibraryPanel.UID] {
+			seen[panel.LibraryPanel.UID] = true
+			uids = append(uids, panel.LibraryPanel.UID)
+		}
+	}
+	return uids
+}
+
+func resolvePanels(panels []Panel, library map[string]LibraryPanel) ([]Panel, error) {
+	ret := []Panel{}
+	for _, panel := range panels {
+		if element, ok := library[refUID(panel.LibraryPanel)]; ok {
+			data, err := json.Marshal(element.Model)
+			if err != nil {
+				return nil, err
+			}
+			resolved := Panel{}
+			if err = json.Unmarshal(data, &resolved); err != nil {
+				return nil, err
+			}
+			resolved.Id = panel.Id
+			resolved.GridPos = panel.GridPos
+			resolved.LibraryPanel = panel.LibraryPanel
+			panel = resolved
+		}
+		if panel.Panels != nil {
+			nested, err := resolvePanels(panel.Panels, library)
+			if err != nil {
+				return nil, err
+			}
+			panel.Panels = nested
+		}
+		ret = append(ret, panel)
+	}
+	return ret, nil
+}
+
+func refUID(ref *LibraryPanelRef) string {
+	if ref == nil {
+		return ""
+	}
+	return ref.UID
+}
+
+// ResolveLibraryPanels returns copy of dashboard, where panels referring to library panels
+// are replaced with model of library panel. Position and id of panel are kept.
+func (dashboard *DashboardJSON) ResolveLibraryPanels(library map[string]LibraryPanel) (DashboardJSON, error) {
+	resolved := *dashboard
+	panels, err := resolvePanels(dashboard.Dashboard.Panels, library)
+	resolved.Dashboard.Panels = panels
+	return resolved, err
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+const libraryDashboard = `{"dashboard": {"title": "Apps", "panels": [
+	{"id": 1, "type": "row", "title": "Row", "panels": [
+		{"id": 2, "gridPos": {"x": 0, "y": 1}, "libraryPanel": {"uid": "cpu", "name": "CPU usage"}}
+	]},
+	{"id": 3, "gridPos": {"x": 12, "y": 0}, "libraryPanel": {"uid": "cpu", "name": "CPU usage"}}
+]}}`
+
+func TestResolveLibraryPanels(t *testing.T) {
+	dashboard, err := parseDashboardJSON([]byte(libraryDashboard))
+	if err != nil {
+		t.Fatalf("parseDashboardJSON failed due to %v", err)
+	}
+	if uids := dashboard.LibraryPanelUIDs(); len(uids) != 1 || uids[0] != "cpu" {
+		t.Errorf("LibraryPanelUIDs returned %v", uids)
+	}
+	data, err := json.Marshal(dashboard.Dashboard.Panels[1])
+	if err != nil || !strings.Contains(string(data), `"libraryPanel":{"uid":"cpu"`) {
+		t.Errorf("libraryPanel lost from %s (%v)", data, err)
+	}
+	library := map[string]LibraryPanel{"cpu": {UID: "cpu", Name: "CPU usage", Model: map[string]interface{}{
+		"id": 7, "title": "CPU usage", "type": "timeseries", "targets": []interface{}{map[string]interface{}{"refId": "A", "expr": "cpu"}},
+	}}}
+	resolved, err := dashboard.ResolveLibraryPanels(library)
+	if err != nil {
+		t.Fatalf("ResolveLibraryPanels failed due to %v", err)
+	}
+	nested := resolved.Dashboard.Panels[0].Panels[0]
+	if nested.Id != 2 || nested.Title != "CPU usage" || nested.Targets[0].Expr != "cpu" || nested.LibraryPanel == nil {
+		t.Errorf("nested panel was not resolved: %#v", nested)
+	}
+	if dashboard.Dashboard.Panels[1].Title != "" {
+		t.Errorf("original dashboard was modified")
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/library-elements/missing" {
+			http.Error(w, `{"message":"library element could not be found"}`, http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"result": {"uid": "cpu", "name": "CPU usage", "version": 3}}`))
+	}))
+	defer server.Close()
+	grafana := config.Grafana{Name: "test", URL: server.URL}
+	if _, err := GetLibraryPanel(grafana, "missing"); !IsNotFound(err) {
+		t.Errorf("IsNotFound returned false for %v", err)
+	}
+	panel, err := GetLibraryPanel(grafana, "cpu")
+	if err != nil || IsNotFound(err) || panel.Version != 3 {
+		t.Errorf("GetLibraryPanel returned %#v, %v", panel, err)
+	}
+}
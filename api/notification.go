@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// Redacted is value that grafana returns instead of secure settings of contact point.
+const Redacted = "[REDACTED]"
+
+// ContactPoint is item in /api/v1/provisioning/contact-points.
+// Contact point with many integrations is returned as many items with same name.
+type ContactPoint struct {
+	UID                   string                 `json:"uid,omitempty"`
+	Name                  string                 `json:"name"`
+	Type                  string                 `json:"type"`
+	Settings              map[string]interface{} `json:"settings"`
+	DisableResolveMessage bool                   `json:"disableResolveMessage"`
+	Provenance            string                 `json:"provenance,omitempty"`
+}
+
+// Policy is node of notification policy tree in /api/v1/provisioning/policies
+type Policy struct {
+	Receiver            string     `json:"receiver,omitempty"`
+	GroupBy             []string   `json:"group_by,omitempty"`
+	ObjectMatchers      [][]string `json:"object_matchers,omitempty"`
+	MuteTimeIntervals   []string   `json:"mute_time_intervals,omitempty"`
+	ActiveTimeIntervals []string   `json:"active_time_intervals,omitempty"`
+	Continue            bool       `json:"continue,omitempty"`
+	GroupWait           string     `json:"group_wait,omitempty"`
+	GroupInterval       string     `json:"group_interval,omitempty"`
+	RepeatInterval      string     `json:"repeat_interval,omitempty"`
+	Routes              []Policy   `json:"routes,omitempty"`
+	Provenance          string     `json:"provenance,omitempty"`
+}
+
+// MuteTiming is item in /api/v1/provisioning/mute-timings
+type MuteTiming struct {
+	Name          string        `json:"name"`
+	TimeIntervals []interface{} `json:"time_intervals"`
+	Version       string        `json:"version,omitempty"`
+	Provenance    string        `json:"provenance,omitempty"`
+}
+
+// NotificationTemplate is item in /api/v1/provisioning/templates
+type NotificationTemplate struct {
+	Name       string `json:"name"`
+	Template   string `json:"template"`
+	Version    string `json:"version,omitempty"`
+	Provenance string `json:"provenance,omitempty"`
+}
+
+func getList[T any](grafana config.Grafana, path string) ([]T, error) {
+	body, err := getBody(grafana, path)
+	if err != nil {
+		return nil, err
+	}
+	items := []T{}
+	if string(body) == "null" {
+		return items, nil
+	}
+	err = json.Unmarshal(body, &items)
+	return items, err
+}
+
+func GetContactPoints(grafana config.Grafana) ([]ContactPoint, error) {
+	return getList[ContactPoint](grafana, "/api/v1/provisioning/contact-points")
+}
+
+// PutContactPoint creates contact point, when uid is empty. Otherwise it updates existing one.
+func PutContactPoint(grafana config.Grafana, cp ContactPoint) error {
+	cp.Provenance = ""
+	if cp.UID == "" {
+		_, err := postBody(grafana, "/api/v1/provisioning/contact-points", cp)
+		return err
+	}
+	_, err := putBody(grafana, "/api/v1/provisioning/contact-points/"+url.PathEscape(cp.UID), cp)
+	return err
+}
+
+func GetPolicies(grafana config.Grafana) (Policy, error) {
+	body, err := getBody(grafana, "/api/v1/provisioning/policies")
+	if err != nil {
+		return Policy{}, err
+	}
+	policy := Policy{}
+	err = json.Unmarshal(body, &policy)
+	return policy, err
+}
+
+// PutPolicies replaces whole notification policy tree.
+func PutPolicies(grafana config.Grafana, policy Policy) error {
+	policy.Provenance = ""
+	_, err := putBody(grafana, "/api/v1/provisioning/policies", policy)
+	return err
+}
+
+func GetMuteTimings(grafana config.Grafana) ([]MuteTiming, error) {
+	return getList[MuteTiming](grafana, "/api/v1/provisioning/mute-timings")
+}
+
+// PutMuteTiming creates or updates mute timing.
+func PutMuteTiming(grafana config.Grafana, timing MuteTiming, exists bool) error {
+	timing.Provenance = ""
+	if !exists {
+		timing.Version = ""
+		_, err := postBody(grafana, "/api/v1/provisioning/mute-timings", timing)
+		return err
+	}
+	_, err := putBody(grafana, "/api/v1/provisioning/mute-timings/"+url.PathEscape(timing.Name), timing)
+	return err
+}
+
+func GetTemplates(grafana config.Grafana) ([]NotificationTemplate, error) {
+	return getList[NotificationTemplate](grafana, "/api/v1/provisioning/templates")
+}
+
+// PutTemplate creates or updates notification template.
+func PutTemplate(grafana config.Grafana, tmpl NotificationTemplate, exists bool) error {
+	tmpl.Provenance = ""
+	if !exists {
+		tmpl.Version = ""
+	}
+	_, err := putBody(grafana, "/api/v1/provisioning/templates/"+url.PathEscape(tmpl.Name), tmpl)
+	return err
+}
@@ -4,23 +4,32 @@ import (
 	"encoding/json"
 )
 
+// LibraryPanelRef is reference from dashboard panel to library panel.
+type LibraryPanelRef struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
 // Panel is part of DashboardJSON.Dashboard.Panels
 type Panel struct {
-	DataSource      interface{} `json:"datasource,omitempty"` // string or DashDataSource
-	Description     interface{} `json:"description,omitempty"`
-	FieldConfig     interface{} `json:"fieldConfig,omitempty"`
-	Collapsed       interface{} `json:"collapsed,omitempty"`
-	GridPos         interface{} `json:"gridPos"`
-	Id              int         `json:"id"`
-	Links           interface{} `json:"links,omitempty"`
-	MaxDataPoints   interface{} `json:"maxDataPoints,omitempty"`
-	Options         interface{} `json:"options,omitempty"`
-	PluginVersion   string      `json:"pluginVersion,omitempty"`
-	Targets         []Target    `json:"targets,omitempty"`
-	Panels          []Panel     `json:"panels"`
-	Title           string      `json:"title"`
-	Transformations interface{} `json:"transformations,omitempty"`
-	Type            string      `json:"type"`
+	DataSource      interface{}      `json:"datasource,omitempty"` // string or DashDataSource
+	Description     interface{}      `json:"description,omitempty"`
+	FieldConfig     interface{}      `json:"fieldConfig,omitempty"`
+	Collapsed       interface{}      `json:"collapsed,omitempty"`
+	GridPos         interface{}      `json:"gridPos"`
+	Id              int              `json:"id"`
+	LibraryPanel    *LibraryPanelRef `json:"libraryPanel,omitempty"`
+	Links           interface{}      `json:"links,omitempty"`
+	MaxDataPoints   interface{}      `json:"maxDataPoints,omitempty"`
+	Options         interface{}      `json:"options,omitempty"`
+	PluginVersion   string           `json:"pluginVersion,omitempty"`
+	Repeat          string           `json:"repeat,omitempty"` // name of variable
+	RepeatDirection string           `json:"repeatDirection,omitempty"`
+	Targets         []Target         `json:"targets,omitempty"`
+	Panels          []Panel          `json:"panels"`
+	Title           string           `json:"title"`
+	Transformations interface{}      `json:"transformations,omitempty"`
+	Type            string           `json:"type"`
 }
 
 func (panel *Panel) Flatten() []Panel {
@@ -50,20 +59,23 @@ func (panel *Panel) MarshalJSON() ([]byte, error) {
 	}
 	// cpanel should be copy of Panel without Panels field
 	cpanel := struct {
-		DataSource      interface{} `json:"datasource,omitempty"` // string or DashDataSource
-		Description     interface{} `json:"description,omitempty"`
-		FieldConfig     interface{} `json:"fieldConfig,omitempty"`
-		Collapsed       interface{} `json:"collapsed,omitempty"`
-		GridPos         interface{} `json:"gridPos"`
-		Id              int         `json:"id"`
-		Links           interface{} `json:"links,omitempty"`
-		MaxDataPoints   interface{} `json:"maxDataPoints,omitempty"`
-		Options         interface{} `json:"options,omitempty"`
-		PluginVersion   string      `json:"pluginVersion,omitempty"`
-		Targets         []Target    `json:"targets,omitempty"`
-		Title           string      `json:"title,omitempty"`
-		Transformations interface{} `json:"transformations,omitempty"`
-		Type            string      `json:"type"`
+		DataSource      interface{}      `json:"datasource,omitempty"` // string or DashDataSource
+		Description     interface{}      `json:"description,omitempty"`
+		FieldConfig     interface{}      `json:"fieldConfig,omitempty"`
+		Collapsed       interface{}      `json:"collapsed,omitempty"`
+		GridPos         interface{}      `json:"gridPos"`
+		Id              int              `json:"id"`
+		LibraryPanel    *LibraryPanelRef `json:"libraryPanel,omitempty"`
+		Links           interface{}      `json:"links,omitempty"`
+		MaxDataPoints   interface{}      `json:"maxDataPoints,omitempty"`
+		Options         interface{}      `json:"options,omitempty"`
+		PluginVersion   string           `json:"pluginVersion,omitempty"`
+		Repeat          string           `json:"repeat,omitempty"`
+		RepeatDirection string           `json:"repeatDirection,omitempty"`
+		Targets         []Target         `json:"targets,omitempty"`
+		Title           string           `json:"title,omitempty"`
+		Transformations interface{}      `json:"transformations,omitempty"`
+		Type            string           `json:"type"`
 	}{
 		DataSource:      panel.DataSource,
 		Description:     panel.Description,
@@ -71,10 +83,13 @@ func (panel *Panel) MarshalJSON() ([]byte, error) {
 		Collapsed:       panel.Collapsed,
 		GridPos:         panel.GridPos,
 		Id:              panel.Id,
+		LibraryPanel:    panel.LibraryPanel,
 		Links:           panel.Links,
 		MaxDataPoints:   panel.MaxDataPoints,
 		Options:         panel.Options,
 		PluginVersion:   panel.PluginVersion,
+		Repeat:          panel.Repeat,
+		RepeatDirection: panel.RepeatDirection,
 		Targets:         panel.Targets,
 		Title:           panel.Title,
 		Transformations: panel.Transformations,
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// Permission is item of dashboard or folder permissions. Exactly one of user,
+// team and role is set. Permission is 1 (View), 2 (Edit) or 4 (Admin).
+type Permission struct {
+	UserId         int    `json:"userId,omitempty"`
+	UserLogin      string `json:"userLogin,omitempty"`
+	TeamId         int    `json:"teamId,omitempty"`
+	Team           string `json:"team,omitempty"`
+	Role           string `json:"role,omitempty"`
+	Permission     int    `json:"permission"`
+	PermissionName string `json:"permissionName,omitempty"`
+	Inherited      bool   `json:"inherited,omitempty"`
+}
+
+// Subject identifies permission holder by name, so that it can be compared across servers.
+func (p Permission) Subject() string {
+	switch {
+	case p.TeamId != 0 || p.Team != "":
+		return "team:" + p.Team
+	case p.UserId != 0 || p.UserLogin != "":
+		return "user:" + p.UserLogin
+	}
+	return "role:" + p.Role
+}
+
+// Level returns name of permission.
+func (p Permission) Level() string {
+	switch p.Permission {
+	case 1:
+		return "View"
+	case 2:
+		return "Edit"
+	case 4:
+		return "Admin"
+	}
+	return p.PermissionName
+}
+
+func getPermissions(grafana config.Grafana, path string) ([]Permission, error) {
+	body, err := getBody(grafana, path)
+	if err != nil {
+		return nil, err
+	}
+	perms := []Permission{}
+	err = json.Unmarshal(body, &perms)
+	return perms, err
+}
+
+// setPermissions replaces permissions, which aren't inherited.
+func setPermissions(grafana config.Grafana, path string, perms []Permission) error {
+	items := []map[string]interface{}{}
+	for _, perm := range perms {
+		item := map[string]interface{}{"permission": perm.Permission}
+		switch {
+		case perm.TeamId != 0:
+			item["teamId"] = perm.TeamId
+		case perm.UserId != 0:
+			item["userId"] = perm.UserId
+		default:
+			item["role"] = perm.Role
+		}
+		items = append(items, item)
+	}
+	_, err := postBody(grafana, path, map[string]interface{}{"items": items})
+	return err
+}
+
+func dashboardPermissionsPath(uid string) string {
+	return "/api/dashboards/uid/" + url.PathEscape(uid) + "/permissions"
+}
+
+func folderPermissionsPath(uid string) string {
+	return "/api/folders/" + url.PathEscape(uid) + "/permissions"
+}
+
+func GetDashboardPermissions(grafana config.Grafana, uid string) ([]Permission, error) {
+	return getPermissions(grafana, dashboardPermissionsPath(uid))
+}
+
+func SetDashboardPermissions(grafana config.Grafana, uid string, perms []Permission) error {
+	return setPermissions(grafana, dashboardPermissionsPath(uid), perms)
+}
+
+func GetFolderPermissions(grafana config.Grafana, uid string) ([]Permission, error) {
+	return getPermissions(grafana, folderPermissionsPath(uid))
+}
+
+func SetFolderPermissions(grafana config.Grafana, uid string, perms []Permission) error {
+	return setPermissions(grafana, folderPermissionsPath(uid), perms)
+}
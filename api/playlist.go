@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// PlaylistItem is dashboard or dashboards with tag in playlist.
+// Type is dashboard_by_uid, dashboard_by_id (deprecated) or dashboard_by_tag.
+type PlaylistItem struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Title string `json:"title,omitempty"`
+}
+
+// Playlist is item in /api/playlists. Items are returned only by GetPlaylist.
+type Playlist struct {
+	Id       int            `json:"id,omitempty"`
+	UID      string         `json:"uid"`
+	Name     string         `json:"name"`
+	Interval string         `json:"interval"`
+	Items    []PlaylistItem `json:"items,omitempty"`
+}
+
+func GetPlaylists(grafana config.Grafana) ([]Playlist, error) {
+	return getList[Playlist](grafana, "/api/playlists")
+}
+
+// GetPlaylist returns playlist with its items.
+func GetPlaylist(grafana config.Grafana, uid string) (Playlist, error) {
+	body, err := getBody(grafana, "/api/playlists/"+url.PathEscape(uid))
+	if err != nil {
+		return Playlist{}, err
+	}
+	playlist := Playlist{}
+	err = json.Unmarshal(body, &playlist)
+	return playlist, err
+}
+
+// PutPlaylist creates playlist or, when exists is true, updates existing one.
+func PutPlaylist(grafana config.Grafana, playlist Playlist, exists bool) error {
+	playlist.Id = 0
+	if !exists {
+		_, err := postBody(grafana, "/api/playlists", playlist)
+		return err
+	}
+	_, err := putBody(grafana, "/api/playlists/"+url.PathEscape(playlist.UID), playlist)
+	return err
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// Plugin is item in /api/plugins
+type Plugin struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"` // panel, datasource or app
+	Enabled   bool   `json:"enabled"`
+	Signature string `json:"signature"`
+	Info      struct {
+		Version string `json:"version"`
+	} `json:"info"`
+}
+
+func GetPlugins(grafana config.Grafana) ([]Plugin, error) {
+	body, err := getBody(grafana, "/api/plugins?embedded=0")
+	if err != nil {
+		return nil, err
+	}
+	plugins := []Plugin{}
+	err = json.Unmarshal(body, &plugins)
+	return plugins, err
+}
+
+// builtinTypes aren't plugins, even though they are used as panel or data source types.
+var builtinTypes = map[string]bool{"row": true, "datasource": true, "grafana": true, "dashboard": true}
+
+func addPlugin(plugins map[string]string, id, version string) {
+	if id == "" || builtinTypes[id] || strings.HasPrefix(id, "__") || strings.HasPrefix(id, "-- ") {
+		return
+	}
+	if current, ok := plugins[id]; !ok || CompareVersions(version, current) > 0 {
+		plugins[id] = version
+	}
+}
+
+// Plugins returns plugins, which dashboard uses, with highest plugin version found from its panels.
+// Version is empty for data sources and panels without pluginVersion.
+func (dashboard *DashboardJSON) Plugins() map[string]string {
+	plugins := map[string]string{}
+	for _, panel := range dashboard.Flatten() {
+		addPlugin(plugins, panel.Type, panel.PluginVersion)
+		if ds, ok := panel.DataSource.(map[string]interface{}); ok {
+			dsType, _ := ds["type"].(string)
+			addPlugin(plugins, dsType, "")
+		}
+		for _, target := range panel.Targets {
+			addPlugin(plugins, target.DataSource.Type, "")
+		}
+	}
+	for _, variable := range dashboard.Dashboard.Templating.List {
+		if ds, ok := variable.DataSource.(map[string]interface{}); ok {
+			dsType, _ := ds["type"].(string)
+			addPlugin(plugins, dsType, "")
+		}
+	}
+	return plugins
+}
+
+// CompareVersions compares dotted version numbers. Suffixes like -beta are ignored.
+// It returns -1, 0 or 1 like strings.Compare.
+func CompareVersions(one, two string) int {
+	parse := func(version string) []int {
+		version, _, _ = strings.Cut(strings.TrimPrefix(version, "v"), "-")
+		parts := []int{}
+		for _, part := range strings.Split(version, ".") {
+			number, _ := strconv.Atoi(part)
+			parts = append(parts, number)
+		}
+		return parts
+	}
+	oneParts := parse(one)
+	twoParts := parse(two)
+	for idx := 0; idx < max(len(oneParts), len(twoParts)); idx++ {
+		oneNum, twoNum := 0, 0
+		if idx < len(oneParts) {
+			oneNum = oneParts[idx]
+		}
+		if idx < len(twoParts) {
+			twoNum = twoParts[idx]
+		}
+		switch {
+		case oneNum < twoNum:
+			return -1
+		case oneNum > twoNum:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package api
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		one, two  string
+		expecting int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"v2.0", "2.0.1", -1},
+		{"10.4.0-beta1", "10.4", 0},
+		{"", "1.0", -1},
+	}
+	for _, test := range tests {
+		if value := CompareVersions(test.one, test.two); value != test.expecting {
+			t.Errorf("CompareVersions(%s, %s) returned %d instead of %d", test.one, test.two, value, test.expecting)
+		}
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	dashboard := DashboardJSON{}
+	dashboard.Dashboard.Panels = []Panel{
+		{Type: "row", Panels: []Panel{{Type: "grafana-clock-panel", PluginVersion: "2.1.0"}}},
+		{Type: "grafana-clock-panel", PluginVersion: "2.1.3", Targets: []Target{{DataSource: DashDataSource{Type: "loki"}}}},
+		{Type: "timeseries", DataSource: map[string]interface{}{"type": "__expr__"}},
+	}
+	plugins := dashboard.Plugins()
+	expecting := map[string]string{"grafana-clock-panel": "2.1.3", "loki": "", "timeseries": ""}
+	if len(plugins) != len(expecting) {
+		t.Errorf("Plugins returned %#v", plugins)
+	}
+	for id, version := range expecting {
+		if value, ok := plugins[id]; !ok || value != version {
+			t.Errorf("Plugins returned %q for %s instead of %q", value, id, version)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// step is one part of rule path. Either key of object or selector of list is set.
+type step struct {
+	key      string
+	selector string
+}
+
+func parsePath(path string) ([]step, error) {
+	steps := []step{}
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in path")
+			}
+			steps = append(steps, step{selector: path[1:end]})
+			path = path[end+1:]
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			steps = append(steps, step{key: path[:end]})
+			path = path[end:]
+		}
+	}
+	return steps, nil
+}
+
+// selects tells if list item at index matches selector.
+func (s step) selects(index int, item interface{}) bool {
+	if s.selector == "*" {
+		return true
+	}
+	if key, value, ok := strings.Cut(s.selector, "="); ok {
+		m, ok := item.(map[string]interface{})
+		return ok && m[key] != nil && fmt.Sprint(m[key]) == value
+	}
+	number, err := strconv.Atoi(s.selector)
+	return err == nil && number == index
+}
+
+// walk calls fn for values at path and replaces them with its return value.
+// Missing keys are created only for the last step.
+func walk(value interface{}, path []step, fn func(interface{}) interface{}) interface{} {
+	if len(path) == 0 {
+		return fn(value)
+	}
+	current := path[0]
+	if current.key != "" {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		item, found := m[current.key]
+		if !found && len(path) > 1 {
+			return value
+		}
+		if changed := walk(item, path[1:], fn); found || changed != nil {
+			m[current.key] = changed
+		}
+		return value
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return value
+	}
+	for idx, item := range list {
+		if current.selects(idx, item) {
+			list[idx] = walk(item, path[1:], fn)
+		}
+	}
+	return value
+}
+
+// matchingPanels returns panels, nested ones included, whose title matches expr.
+func matchingPanels(value interface{}, expr *regexp.Regexp) []interface{} {
+	ret := []interface{}{}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return ret
+	}
+	panels, _ := m["panels"].([]interface{})
+	for _, item := range panels {
+		if panel, ok := item.(map[string]interface{}); ok {
+			if title, _ := panel["title"].(string); expr.MatchString(title) {
+				ret = append(ret, panel)
+			}
+		}
+		ret = append(ret, matchingPanels(item, expr)...)
+	}
+	return ret
+}
+
+func ruleFunc(rule config.Rule) (func(interface{}) interface{}, error) {
+	switch {
+	case rule.Set != nil:
+		return func(interface{}) interface{} { return rule.Set }, nil
+	case rule.Replace != "":
+		return func(value interface{}) interface{} {
+			if text, ok := value.(string); ok {
+				return strings.ReplaceAll(text, rule.Replace, rule.With)
+			}
+			return value
+		}, nil
+	}
+	expr, err := regexp.Compile(rule.Regex)
+	if err != nil {
+		return nil, err
+	}
+	return func(value interface{}) interface{} {
+		if text, ok := value.(string); ok {
+			return expr.ReplaceAllString(text, rule.With)
+		}
+		return value
+	}, nil
+}
+
+func applyRule(content map[string]interface{}, rule config.Rule) error {
+	title, _ := content["title"].(string)
+	dashExpr, err := regexp.Compile(rule.Dashboard)
+	if err != nil || !dashExpr.MatchString(title) {
+		return err
+	}
+	path, err := parsePath(rule.Path)
+	if err != nil {
+		return fmt.Errorf("error in path %s: %w", rule.Path, err)
+	}
+	fn, err := ruleFunc(rule)
+	if err != nil {
+		return err
+	}
+	roots := []interface{}{content}
+	if rule.Panel != "" {
+		panelExpr, err := regexp.Compile(rule.Panel)
+		if err != nil {
+			return err
+		}
+		roots = matchingPanels(content, panelExpr)
+	}
+	for _, root := range roots {
+		walk(root, path, fn)
+	}
+	return nil
+}
+
+// Transform returns copy of dashboard with rules applied in order.
+func (dashboard *DashboardJSON) Transform(rules []config.Rule) (DashboardJSON, error) {
+	if len(rules) == 0 {
+		return *dashboard, nil
+	}
+	content, err := dashboard.Content()
+	if err != nil {
+		return *dashboard, err
+	}
+	for _, rule := range rules {
+		if err = applyRule(content, rule); err != nil {
+			return *dashboard, err
+		}
+	}
+	return dashboard.WithContent(content)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+func TestTransform(t *testing.T) {
+	original, err := parseDashboardJSON([]byte(appDebug))
+	if err != nil {
+		t.Fatalf("parseDashboardJSON failed due to %v", err)
+	}
+	rules := []config.Rule{
+		{Path: "title", Regex: "^App", With: "[PROD] App"},
+		{Path: "templating.list[name=App].current", Set: map[string]interface{}{"text": "prod", "value": "prod"}},
+		{Panel: "^Memory", Path: "targets[*].expr", Replace: "container_memory", With: "pod_memory"},
+		{Dashboard: "^Other$", Path: "description", Set: "not applied"},
+	}
+	transformed, err := original.Transform(rules)
+	if err != nil {
+		t.Fatalf("Transform failed due to %v", err)
+	}
+	if title := transformed.Dashboard.Title; title != "[PROD] App Debug" {
+		t.Errorf("wrong title: %s", title)
+	}
+	current, _ := transformed.Dashboard.Templating.List[0].Current.(map[string]interface{})
+	if current["value"] != "prod" {
+		t.Errorf("wrong variable current: %#v", transformed.Dashboard.Templating.List[0].Current)
+	}
+	if expr := transformed.Dashboard.Panels[0].Targets[0].Expr; !strings.HasPrefix(expr, "max(pod_memory_usage_bytes") {
+		t.Errorf("expr was not replaced: %s", expr)
+	}
+	if expr := transformed.Dashboard.Panels[2].Targets[0].Expr; !strings.Contains(expr, "container_memory") {
+		t.Errorf("expr of other panel was replaced: %s", expr)
+	}
+	if transformed.Dashboard.Description != "" || original.Dashboard.Title != "App Debug" {
+		t.Errorf("rule for other dashboard was applied or original was modified")
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	steps, err := parsePath("templating.list[name=a.b].current")
+	if err != nil {
+		t.Fatalf("parsePath failed due to %v", err)
+	}
+	if len(steps) != 4 || steps[2].selector != "name=a.b" || steps[3].key != "current" {
+		t.Errorf("wrong steps: %#v", steps)
+	}
+	if _, err = parsePath("panels[0"); err == nil {
+		t.Errorf("parsePath should fail on missing ]")
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// VariableRef is reference to dashboard variable. Location tells where reference was found.
+type VariableRef struct {
+	Name     string
+	Location string
+}
+
+// variableRef matches $var, ${var}, ${var:format} and [[var]].
+var variableRef = regexp.MustCompile(`\$(\w+)|\$\{(\w+)(?::[^}]*)?\}|\[\[(\w+)(?::[^\]]*)?\]\]`)
+
+// globalVariables are provided by Grafana and don't have to be defined. Names starting with __ are global too.
+var globalVariables = map[string]bool{"interval": true, "interval_ms": true, "timeFilter": true}
+
+// ParseVariableRefs returns names of variables referenced in text. Global variables
+// and regexp groups like $1 are left out.
+func ParseVariableRefs(text string) []string {
+	names := []string{}
+	for _, match := range variableRef.FindAllStringSubmatch(text, -1) {
+		name := match[1] + match[2] + match[3]
+		if strings.HasPrefix(name, "__") || globalVariables[name] || strings.Trim(name, "0123456789") == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// collectStrings returns strings of JSON value, like links, in stable order.
+func collectStrings(value interface{}) []string {
+	switch item := value.(type) {
+	case string:
+		return []string{item}
+	case []interface{}:
+		texts := []string{}
+		for _, elem := range item {
+			texts = append(texts, collectStrings(elem)...)
+		}
+		return texts
+	case map[string]interface{}:
+		keys := []string{}
+		for key := range item {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		texts := []string{}
+		for _, key := range keys {
+			texts = append(texts, collectStrings(item[key])...)
+		}
+		return texts
+	}
+	return nil
+}
+
+// dataSourceUID returns uid from DashDataSource or name from string form of data source.
+func dataSourceUID(ds interface{}) string {
+	switch item := ds.(type) {
+	case string:
+		return item
+	case map[string]interface{}:
+		uid, _ := item["uid"].(string)
+		return uid
+	case DashDataSource:
+		return item.UID
+	}
+	return ""
+}
+
+// VariableRefs returns references to variables from panel titles, links, data sources,
+// repeats, text panel content and queries, and from queries of other variables.
+func (dashboard *DashboardJSON) VariableRefs() []VariableRef {
+	refs := []VariableRef{}
+	add := func(location string, texts ...string) {
+		for _, text := range texts {
+			for _, name := range ParseVariableRefs(text) {
+				refs = append(refs, VariableRef{Name: name, Location: location})
+			}
+		}
+	}
+	add("links", collectStrings(dashboard.Dashboard.Links)...)
+	for _, variable := range dashboard.Dashboard.Templating.List {
+		location := "variable " + variable.Name
+		query, _ := variable.Query.(string)
+		for _, text := range []string{variable.Definition, query, dataSourceUID(variable.DataSource)} {
+			for _, name := range ParseVariableRefs(text) {
+				if name != variable.Name {
+					refs = append(refs, VariableRef{Name: name, Location: location})
+				}
+			}
+		}
+	}
+	for _, panel := range dashboard.Flatten() {
+		location := "panel " + panel.Title
+		add(location+" title", panel.Title)
+		add(location+" links", collectStrings(panel.Links)...)
+		add(location+" datasource", dataSourceUID(panel.DataSource))
+		if panel.Repeat != "" {
+			refs = append(refs, VariableRef{Name: panel.Repeat, Location: location + " repeat"})
+		}
+		if options, ok := panel.Options.(map[string]interface{}); ok && panel.Type == "text" {
+			content, _ := options["content"].(string)
+			add(location+" content", content)
+		}
+		for _, target := range panel.Targets {
+			query, _ := target.Query.(string)
+			add(location+" target "+target.RefId, target.Expr, query, target.DataSource.UID)
+		}
+	}
+	return refs
+}
+
+// UnusedVariables returns names of defined variables, which aren't referenced.
+// Ad hoc filters are applied without references, so they are never unused.
+func (dashboard *DashboardJSON) UnusedVariables() []string {
+	used := map[string]bool{}
+	for _, ref := range dashboard.VariableRefs() {
+		used[ref.Name] = true
+	}
+	unused := []string{}
+	for _, variable := range dashboard.Dashboard.Templating.List {
+		if variable.Type != "adhoc" && !used[variable.Name] {
+			unused = append(unused, variable.Name)
+		}
+	}
+	return unused
+}
+
+// UndefinedVariables returns references to variables, which aren't defined in templating.
+func (dashboard *DashboardJSON) UndefinedVariables() []VariableRef {
+	defined := map[string]bool{}
+	for _, variable := range dashboard.Dashboard.Templating.List {
+		defined[variable.Name] = true
+	}
+	undefined := []VariableRef{}
+	for _, ref := range dashboard.VariableRefs() {
+		if !defined[ref.Name] {
+			undefined = append(undefined, ref)
+		}
+	}
+	return undefined
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVariableRefs(t *testing.T) {
+	tests := []struct {
+		text      string
+		expecting []string
+	}{
+		{`rate(http{job="$job"}[$__rate_interval])`, []string{"job"}},
+		{`sum by (pod) (x{ns=~"${namespace:regex}", env="[[env]]"})`, []string{"namespace", "env"}},
+		{`label_replace(up, "host", "$1", "instance", "(.*):.*") $timeFilter`, []string{}},
+		{`up{job=~"api.*$"}`, []string{}},
+	}
+	for _, test := range tests {
+		if names := ParseVariableRefs(test.text); !reflect.DeepEqual(names, test.expecting) {
+			t.Errorf("ParseVariableRefs(%s) returned %v instead of %v", test.text, names, test.expecting)
+		}
+	}
+}
+
+func TestVariableRefs(t *testing.T) {
+	dashboard := DashboardJSON{}
+	dashboard.Dashboard.Links = []interface{}{map[string]interface{}{"url": "/d/abc?var-env=${env}"}}
+	dashboard.Dashboard.Templating.List = []Variable{
+		{Name: "env", Type: "custom"},
+		{Name: "pod", Type: "query", Query: `label_values(up{env="$env", pod=~"$pod"}, pod)`},
+		{Name: "unused", Type: "constant"},
+		{Name: "filters", Type: "adhoc"},
+		{Name: "team", Type: "custom"},
+	}
+	dashboard.Dashboard.Panels = []Panel{
+		{Title: "CPU of $pod", Targets: []Target{{RefId: "A", Expr: `cpu{region="$region"}`}}},
+		{Title: "Nodes", Repeat: "node", RepeatDirection: "h"},
+		{Title: "Docs", Type: "text", Options: map[string]interface{}{"content": "Runbook of ${team}"}},
+	}
+	if unused := dashboard.UnusedVariables(); !reflect.DeepEqual(unused, []string{"unused"}) {
+		t.Errorf("UnusedVariables returned %v", unused)
+	}
+	expecting := []VariableRef{
+		{Name: "region", Location: "panel CPU of $pod target A"}, {Name: "node", Location: "panel Nodes repeat"},
+	}
+	if undefined := dashboard.UndefinedVariables(); !reflect.DeepEqual(undefined, expecting) {
+		t.Errorf("UndefinedVariables returned %v", undefined)
+	}
+}
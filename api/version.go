@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// [
+//
+//	{
+//		"id":2,"dashboardId":1,"uid":"QA7wKklGz","parentVersion":1,"restoredFrom":0,"version":2,
+//		"created":"2017-06-08T17:24:33-04:00","createdBy":"admin","message":"Updated panel title"
+//	},
+//	...
+//
+// ]
+type DashboardVersion struct {
+	Id            int    `json:"id"`
+	DashboardId   int    `json:"dashboardId"`
+	UID           string `json:"uid"`
+	ParentVersion int    `json:"parentVersion"`
+	RestoredFrom  int    `json:"restoredFrom"`
+	Version       int    `json:"version"`
+	Created       string `json:"created"`
+	CreatedBy     string `json:"createdBy"`
+	Message       string `json:"message"`
+}
+
+// dashboardVersionJSON is response from /api/dashboards/uid/:uid/versions/:id
+type dashboardVersionJSON struct {
+	DashboardVersion
+	Data json.RawMessage `json:"data"`
+}
+
+// GetVersions returns list of versions that Grafana has stored from dashboard.
+func (board *Dashboard) GetVersions() ([]DashboardVersion, error) {
+	path := fmt.Sprintf("/api/dashboards/uid/%s/versions", board.UID)
+	body, err := getBody(board.grafana, path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDashboardVersions(body)
+}
+
+// parseDashboardVersions deals with the fact that older Grafanas return
+// plain list, while newer ones wrap the list into `versions` field.
+func parseDashboardVersions(body []byte) ([]DashboardVersion, error) {
+	versions := []DashboardVersion{}
+	if err := json.Unmarshal(body, &versions); err == nil {
+		return versions, nil
+	}
+	wrapped := struct {
+		Versions []DashboardVersion `json:"versions"`
+	}{}
+	err := json.Unmarshal(body, &wrapped)
+	return wrapped.Versions, err
+}
+
+// GetVersion fetches dashboard content as it was in given version.
+// Only Dashboard part of DashboardJSON is filled.
+func (board *Dashboard) GetVersion(version DashboardVersion) (DashboardJSON, error) {
+	path := fmt.Sprintf("/api/dashboards/uid/%s/versions/%d", board.UID, version.Id)
+	body, err := getBody(board.grafana, path)
+	if err != nil {
+		return DashboardJSON{}, err
+	}
+	value := dashboardVersionJSON{}
+	if err = json.Unmarshal(body, &value); err != nil {
+		return DashboardJSON{}, err
+	}
+	dboard := DashboardJSON{raw: value.Data}
+	err = json.Unmarshal(value.Data, &dboard.Dashboard)
+	return dboard, err
+}
+
+// Restore rolls dashboard back to given version.
+// Grafana stores the rollback as new version.
+func (board *Dashboard) Restore(version int) error {
+	path := fmt.Sprintf("/api/dashboards/uid/%s/restore", board.UID)
+	_, err := postBody(board.grafana, path, map[string]int{"version": version})
+	return err
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseDashboardVersions(t *testing.T) {
+	items := []string{
+		`[{"id":2,"uid":"abc","version":2,"createdBy":"admin","message":"fix"},{"id":1,"uid":"abc","version":1}]`,
+		`{"continueToken":"","versions":[{"id":2,"uid":"abc","version":2,"createdBy":"admin","message":"fix"},{"id":1,"uid":"abc","version":1}]}`,
+	}
+	for idx, item := range items {
+		t.Run(fmt.Sprintf("TestParseDashboardVersions.%d", idx), func(t *testing.T) {
+			versions, err := parseDashboardVersions([]byte(item))
+			if err != nil {
+				t.Errorf("parseDashboardVersions failed due to %v", err)
+			}
+			if len(versions) != 2 {
+				t.Errorf("Wrong number of versions found (%d vs. 2)", len(versions))
+			}
+			if versions[0].Version != 2 || versions[0].Message != "fix" {
+				t.Errorf("Wrong content in first version: %#v", versions[0])
+			}
+		})
+	}
+}
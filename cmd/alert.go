@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/backup"
+)
+
+// ruleFields flattens comparable fields of alert rule into map.
+// Server specific fields (id, orgID, updated and provenance) are left out.
+func ruleFields(rule api.AlertRule) map[string]string {
+	fields := map[string]string{
+		"title":        rule.Title,
+		"folderUID":    rule.FolderUID,
+		"ruleGroup":    rule.RuleGroup,
+		"condition":    rule.Condition,
+		"for":          rule.For,
+		"noDataState":  rule.NoDataState,
+		"execErrState": rule.ExecErrState,
+		"isPaused":     strconv.FormatBool(rule.IsPaused),
+	}
+	flattenJSON("notificationSettings", rule.NotificationSettings, fields)
+	for key, value := range rule.Labels {
+		fields["labels."+key] = value
+	}
+	for key, value := range rule.Annotations {
+		fields["annotations."+key] = value
+	}
+	for _, query := range rule.Data {
+		prefix := "query." + query.RefId + "."
+		fields[prefix+"datasourceUid"] = query.DataSourceUID
+		fields[prefix+"queryType"] = query.QueryType
+		fields[prefix+"relativeTimeRange"] = fmt.Sprintf("%d-%d", query.RelativeTimeRange.From, query.RelativeTimeRange.To)
+		flattenJSON(prefix+"model", query.Model, fields)
+	}
+	return fields
+}
+
+// rulePair is alert rule on both servers. Missing side is nil.
+type rulePair struct {
+	left  *api.AlertRule
+	right *api.AlertRule
+}
+
+// pairRules matches rules by uid and then remaining ones by title.
+func pairRules(one, two []api.AlertRule) []rulePair {
+	pairs := []rulePair{}
+	used := map[int]bool{}
+	unmatched := []*api.AlertRule{}
+	for idx := range one {
+		pos := -1
+		for jdx := range two {
+			if !used[jdx] && two[jdx].UID == one[idx].UID {
+				pos = jdx
+				break
+			}
+		}
+		if pos < 0 {
+			unmatched = append(unmatched, &one[idx])
+			continue
+		}
+		used[pos] = true
+		pairs = append(pairs, rulePair{left: &one[idx], right: &two[pos]})
+	}
+	for _, rule := range unmatched {
+		pair := rulePair{left: rule}
+		for jdx := range two {
+			if !used[jdx] && two[jdx].Title == rule.Title {
+				used[jdx] = true
+				pair.right = &two[jdx]
+				break
+			}
+		}
+		pairs = append(pairs, pair)
+	}
+	for jdx := range two {
+		if !used[jdx] {
+			pairs = append(pairs, rulePair{right: &two[jdx]})
+		}
+	}
+	return pairs
+}
+
+// diffAlertRuleList compares rules of two servers. Rules of first server are
+// mapped with data source mapping of second server before comparison.
+func diffAlertRuleList(one, two []api.AlertRule, mapping map[string]string) ([][]string, error) {
+	uniqOne := []string{}
+	uniqTwo := []string{}
+	diff := [][]string{}
+	for _, pair := range pairRules(one, two) {
+		switch {
+		case pair.right == nil:
+			uniqOne = append(uniqOne, pair.left.Title)
+			continue
+		case pair.left == nil:
+			uniqTwo = append(uniqTwo, pair.right.Title)
+			continue
+		}
+		mapped, err := pair.left.MapDataSources(mapping)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range diffFields(ruleFields(mapped), ruleFields(*pair.right)) {
+			diff = append(diff, []string{pair.left.Title + "\n" + item[0], item[1], item[2]})
+		}
+	}
+	if len(uniqOne) == 0 && len(uniqTwo) == 0 && len(diff) == 0 {
+		return nil, nil
+	}
+	sort.Strings(uniqOne)
+	sort.Strings(uniqTwo)
+	sort.Slice(diff, func(i, j int) bool { return diff[i][0] < diff[j][0] })
+	return append([][]string{
+		{"Unique Alert Rules", strings.Join(uniqOne, "\n"), strings.Join(uniqTwo, "\n")},
+	}, diff...), nil
+}
+
+func diffAlertRules(server1, server2 config.Grafana, mappings config.Mappings) error {
+	rules1, err1 := api.GetAlertRules(server1)
+	rules2, err2 := api.GetAlertRules(server2)
+	if err := errors.Join(err1, err2); err != nil {
+		return err
+	}
+	diff, err := diffAlertRuleList(rules1, rules2, mappings.DataSourcesBetween(server1.Name, server2.Name))
+	if err != nil {
+		return err
+	}
+	if len(diff) == 0 {
+		slog.Info("alert rules are identical", server1.Name, server2.Name)
+		return nil
+	}
+	renderTable([]string{"", server1.Name, server2.Name}, diff)
+	return ErrFound
+}
+
+func diffAlertsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alerts [server1 server2]",
+		Short: "diff alert rules of two grafanas",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			return exitStatus(cmd, diffAlertRules(server1, server2, cfg.Mappings))
+		},
+	}
+	return cmd
+}
+
+type groupKey struct {
+	folderUID string
+	title     string
+}
+
+func rulesByGroup(rules []api.AlertRule) map[groupKey][]api.AlertRule {
+	groups := map[groupKey][]api.AlertRule{}
+	for _, rule := range rules {
+		key := groupKey{folderUID: rule.FolderUID, title: rule.RuleGroup}
+		groups[key] = append(groups[key], rule)
+	}
+	return groups
+}
+
+// groupPush is mapped source group to be written into target. Current is nil for new groups.
+// Extra rules exist only on target. They are deleted with prune and kept in group otherwise.
+type groupPush struct {
+	key     groupKey
+	group   api.RuleGroup
+	current *api.RuleGroup
+	extra   []api.AlertRule
+	prune   bool
+	changed bool
+}
+
+// planGroupPush compares mapped source group against current group on target.
+func planGroupPush(key groupKey, group api.RuleGroup, current *api.RuleGroup, prune bool) (groupPush, error) {
+	push := groupPush{key: key, group: group, current: current, prune: prune, changed: true}
+	if current == nil {
+		return push, nil
+	}
+	for _, pair := range pairRules(group.Rules, current.Rules) {
+		if pair.left == nil {
+			push.extra = append(push.extra, *pair.right)
+		}
+	}
+	if !prune {
+		push.keep()
+	}
+	diff, err := diffAlertRuleList(push.group.Rules, current.Rules, nil)
+	push.changed = len(diff) > 0 || group.Interval != current.Interval
+	return push, err
+}
+
+// keep adds rules, which exist only on target, into group.
+func (push *groupPush) keep() {
+	push.group.Rules = append(slices.Clone(push.group.Rules), push.extra...)
+	push.prune = false
+}
+
+func (push *groupPush) details(source config.Grafana) string {
+	switch {
+	case len(push.extra) == 0:
+		return ""
+	case push.prune:
+		return fmt.Sprintf("deletes %d rule(s) missing from %s", len(push.extra), source.Name)
+	}
+	return fmt.Sprintf("keeps %d rule(s) missing from %s", len(push.extra), source.Name)
+}
+
+// fetchGroupPush gets rule group from source and maps it for target.
+// Existing is nil, when group doesn't exist on target.
+func fetchGroupPush(
+	source, target config.Grafana, key groupKey, existing []api.AlertRule, mapping map[string]string, prune bool,
+) (groupPush, error) {
+	group, err := api.GetRuleGroup(source, key.folderUID, key.title)
+	if err != nil {
+		return groupPush{}, err
+	}
+	for idx, rule := range group.Rules {
+		if group.Rules[idx], err = rule.MapDataSources(mapping); err != nil {
+			return groupPush{}, err
+		}
+	}
+	if existing == nil {
+		return planGroupPush(key, group, nil, prune)
+	}
+	current, err := api.GetRuleGroup(target, key.folderUID, key.title)
+	if err != nil {
+		return groupPush{}, err
+	}
+	return planGroupPush(key, group, &current, prune)
+}
+
+// confirmRuleDeletions checks deletions of rules against safety limits.
+// If deletions are cancelled, rules are kept on target.
+func confirmRuleDeletions(target config.Grafana, pushes []groupPush, opts pruneOptions, in io.Reader) error {
+	titles := []string{}
+	for _, push := range pushes {
+		for _, rule := range push.extra {
+			if push.changed && push.prune {
+				titles = append(titles, rule.Title)
+			}
+		}
+	}
+	if len(titles) == 0 {
+		return nil
+	}
+	if len(titles) > opts.maxDeletions {
+		return fmt.Errorf("%d alert rules to delete exceeds maximum of %d, nothing pushed",
+			len(titles), opts.maxDeletions)
+	}
+	for _, title := range titles {
+		fmt.Printf("delete %s from %s\n", title, target.Name)
+	}
+	if opts.yes || confirm(in, fmt.Sprintf("Delete %d alert rules?", len(titles))) {
+		return nil
+	}
+	slog.Info("deletions cancelled")
+	for idx := range pushes {
+		if pushes[idx].prune {
+			pushes[idx].keep()
+		}
+	}
+	return nil
+}
+
+// writeGroupPush takes backup of current group on target (if any) before replacing it.
+func writeGroupPush(set *backup.Set, target config.Grafana, push groupPush) (string, error) {
+	if push.current == nil {
+		return "created", api.PutRuleGroup(target, push.group)
+	}
+	if _, err := saveRuleGroup(set, target, *push.current); err != nil {
+		return "", err
+	}
+	return "updated", api.PutRuleGroup(target, push.group)
+}
+
+func pushAlertsCmd() *cobra.Command {
+	var dryRun bool
+	var prune pruneOptions
+	var backupDir string
+	cmd := &cobra.Command{
+		Use:   "push-alerts [source target]",
+		Short: "copy alert rules from one grafana to another",
+		Long: "Create or update rule groups on target to match source. Rules, which exist only on target, " +
+			"are kept unless --prune is given. Current groups are backed up before they are replaced. " +
+			"Data source uids in queries are mapped with mappings.datasources of target. " +
+			"Folders of rule groups should exist on target with same uid",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			source, err1 := getServer(ctx, args[0])
+			target, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			rules1, err1 := api.GetAlertRules(source)
+			rules2, err2 := api.GetAlertRules(target)
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			groups1 := rulesByGroup(rules1)
+			groups2 := rulesByGroup(rules2)
+			keys := []groupKey{}
+			for key := range groups1 {
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].folderUID+"/"+keys[i].title < keys[j].folderUID+"/"+keys[j].title
+			})
+			mapping := cfg.Mappings.DataSourcesBetween(source.Name, target.Name)
+			pushes := []groupPush{}
+			for _, key := range keys {
+				push, err := fetchGroupPush(source, target, key, groups2[key], mapping, prune.enabled)
+				if err != nil {
+					return err
+				}
+				pushes = append(pushes, push)
+			}
+			if !dryRun {
+				if !cmd.Flags().Changed("max-deletions") && cfg.Defaults.MaxDeletions > 0 {
+					prune.maxDeletions = cfg.Defaults.MaxDeletions
+				}
+				if err = confirmRuleDeletions(target, pushes, prune, os.Stdin); err != nil {
+					return err
+				}
+			}
+			set, err := newBackupSet(ctx, backupDir)
+			if err != nil {
+				return err
+			}
+			rows := [][]string{}
+			for _, push := range pushes {
+				action := "unchanged"
+				switch {
+				case !push.changed:
+				case dryRun && push.current == nil:
+					action = "would create"
+				case dryRun:
+					action = "would update"
+				default:
+					if action, err = writeGroupPush(set, target, push); err != nil {
+						return err
+					}
+				}
+				details := ""
+				if push.changed {
+					details = push.details(source)
+				}
+				rows = append(rows, []string{push.key.folderUID, push.key.title, action, details})
+			}
+			renderTable([]string{"Folder", "Rule group", "Action", "Details"}, rows)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show what would be pushed")
+	cmd.Flags().BoolVar(&prune.enabled, "prune", false, "delete rules, which are missing from source, in pushed groups")
+	cmd.Flags().BoolVarP(&prune.yes, "yes", "y", false, "delete without confirmation")
+	cmd.Flags().IntVar(&prune.maxDeletions, "max-deletions", 5, "refuse to delete more rules than this")
+	addBackupFlag(cmd, &backupDir)
+	return cmd
+}
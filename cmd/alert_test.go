@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+func TestDiffAlertRuleList(t *testing.T) {
+	query := api.AlertQuery{RefId: "A", DataSourceUID: "test-prometheus", Model: map[string]interface{}{"expr": "up"}}
+	cpu := api.AlertRule{UID: "cpu", Title: "CPU high", For: "5m", Data: []api.AlertQuery{query}}
+	disk := api.AlertRule{UID: "disk", Title: "Disk full", For: "5m"}
+	prodQuery := query
+	prodQuery.DataSourceUID = "prod-prometheus"
+	prodCPU := cpu
+	prodCPU.UID = "cpu-prod"
+	prodCPU.Data = []api.AlertQuery{prodQuery}
+	prodDisk := disk
+	prodDisk.For = "10m"
+	mem := api.AlertRule{UID: "mem", Title: "Memory high"}
+
+	pairs := pairRules([]api.AlertRule{cpu, disk}, []api.AlertRule{prodDisk, prodCPU, mem})
+	if len(pairs) != 3 || pairs[0].right.UID != "disk" || pairs[1].right.UID != "cpu-prod" || pairs[2].left != nil {
+		t.Errorf("pairRules returned wrong pairs: %#v", pairs)
+	}
+	diff, err := diffAlertRuleList(
+		[]api.AlertRule{cpu, disk}, []api.AlertRule{prodDisk, prodCPU, mem},
+		map[string]string{"test-prometheus": "prod-prometheus"},
+	)
+	if err != nil {
+		t.Fatalf("diffAlertRuleList failed due to %v", err)
+	}
+	if len(diff) != 2 || diff[0][2] != "Memory high" || diff[1][0] != "Disk full\nfor mismatch" {
+		t.Errorf("diffAlertRuleList returned %#v", diff)
+	}
+	if diff, _ = diffAlertRuleList([]api.AlertRule{cpu}, []api.AlertRule{cpu}, nil); diff != nil {
+		t.Errorf("identical rules returned %#v", diff)
+	}
+	expr := `sum(rate(http_requests_total{job="api", code=~"5.."}[5m])) by (instance) > `
+	low := api.AlertRule{UID: "err", Title: "Errors", Data: []api.AlertQuery{
+		{RefId: "A", Model: map[string]interface{}{"expr": expr + "10"}},
+	}}
+	high := api.AlertRule{UID: "err", Title: "Errors", Data: []api.AlertQuery{
+		{RefId: "A", Model: map[string]interface{}{"expr": expr + "99"}},
+	}}
+	diff, _ = diffAlertRuleList([]api.AlertRule{low}, []api.AlertRule{high}, nil)
+	if len(diff) != 2 || diff[1][0] != "Errors\nquery.A.model.expr mismatch" {
+		t.Errorf("difference at the end of long expr returned %#v", diff)
+	}
+}
+
+func TestPlanGroupPush(t *testing.T) {
+	cpu := api.AlertRule{UID: "cpu", Title: "CPU high", For: "5m"}
+	disk := api.AlertRule{UID: "disk", Title: "Disk full", For: "5m"}
+	group := api.RuleGroup{Title: "infra", FolderUID: "ops", Rules: []api.AlertRule{cpu}}
+	current := api.RuleGroup{Title: "infra", FolderUID: "ops", Rules: []api.AlertRule{cpu, disk}}
+	key := groupKey{folderUID: "ops", title: "infra"}
+	tests := []struct {
+		name    string
+		prune   bool
+		answer  string
+		rules   int
+		changed bool
+	}{
+		{"keep", false, "", 2, false},
+		{"prune", true, "y\n", 1, true},
+		{"prune cancelled", true, "n\n", 2, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			push, err := planGroupPush(key, group, &current, test.prune)
+			if err != nil || push.changed != test.changed {
+				t.Fatalf("planGroupPush returned %#v, %v", push, err)
+			}
+			pushes := []groupPush{push}
+			opts := pruneOptions{enabled: test.prune, maxDeletions: 5}
+			if err = confirmRuleDeletions(config.Grafana{Name: "prod"}, pushes, opts, strings.NewReader(test.answer)); err != nil {
+				t.Fatalf("confirmRuleDeletions failed due to %v", err)
+			}
+			if len(pushes[0].group.Rules) != test.rules {
+				t.Errorf("group would be pushed with %d rules instead of %d", len(pushes[0].group.Rules), test.rules)
+			}
+		})
+	}
+	push, _ := planGroupPush(key, group, &current, true)
+	if err := confirmRuleDeletions(config.Grafana{Name: "prod"}, []groupPush{push}, pruneOptions{maxDeletions: 0}, strings.NewReader("y\n")); err == nil {
+		t.Errorf("deletions over maximum were accepted")
+	}
+	if len(group.Rules) != 1 {
+		t.Errorf("source group was modified: %#v", group)
+	}
+}
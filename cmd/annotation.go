@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// parseTime accepts duration before now (like 24h or 7d), RFC3339 or epoch milliseconds.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if count, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -count), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		return ts, nil
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected duration (7d, 24h), RFC3339 or epoch milliseconds", value)
+}
+
+// annotationKey identifies annotation across servers by its time, text, dashboard title and panel.
+// Dashboards, which mapper doesn't know, are identified by uid.
+func annotationKey(annotation api.Annotation, mapper *dashboardMapper) string {
+	key := time.UnixMilli(annotation.Time).UTC().Format(time.RFC3339) + " " + annotation.Text
+	if annotation.DashboardUID == "" && annotation.DashboardId == 0 {
+		return key
+	}
+	dashboard := annotation.DashboardUID
+	if db, ok := mapper.source(annotation.DashboardUID, annotation.DashboardId); ok {
+		dashboard = db.Title
+	}
+	return fmt.Sprintf("%s (%s, panel %d)", key, dashboard, annotation.PanelId)
+}
+
+// annotationFields describes annotation. Dashboard and panel are part of annotationKey.
+func annotationFields(annotation api.Annotation) map[string]string {
+	tags := append([]string{}, annotation.Tags...)
+	sort.Strings(tags)
+	fields := map[string]string{"tags": strings.Join(tags, ", ")}
+	if annotation.TimeEnd != 0 && annotation.TimeEnd != annotation.Time {
+		fields["timeEnd"] = time.UnixMilli(annotation.TimeEnd).UTC().Format(time.RFC3339)
+	}
+	return fields
+}
+
+// remapAnnotation replaces dashboard of source in annotation with dashboard of target.
+// It returns false, if dashboard is missing from target.
+func remapAnnotation(annotation api.Annotation, mapper *dashboardMapper) (api.Annotation, bool) {
+	if annotation.DashboardUID == "" && annotation.DashboardId == 0 {
+		return annotation, true
+	}
+	db, ok := mapper.target(annotation.DashboardUID, annotation.DashboardId)
+	annotation.DashboardUID = db.UID
+	annotation.DashboardId = 0
+	return annotation, ok
+}
+
+// annotationOptions has flags shared by diff and push of annotations.
+type annotationOptions struct {
+	from string
+	to   string
+	tags []string
+}
+
+func (opts *annotationOptions) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVar(&opts.from, "from", "7d", "start of time range as duration before now, RFC3339 or epoch ms")
+	flags.StringVar(&opts.to, "to", "", "end of time range (default now)")
+	flags.StringSliceVar(&opts.tags, "tag", nil, "only annotations with all given tags")
+}
+
+func (opts annotationOptions) filter() (api.AnnotationFilter, error) {
+	now := time.Now()
+	from, err1 := parseTime(opts.from, now)
+	to, err2 := parseTime(opts.to, now)
+	if err := errors.Join(err1, err2); err != nil {
+		return api.AnnotationFilter{}, err
+	}
+	return api.AnnotationFilter{From: from, To: to, Tags: opts.tags, Limit: 10000}, nil
+}
+
+// annotationState has annotations and dashboards of two servers.
+type annotationState struct {
+	one, two map[string]api.Annotation
+	mapper   *dashboardMapper
+}
+
+func getAnnotationState(server1, server2 config.Grafana, opts annotationOptions) (annotationState, error) {
+	filter, err := opts.filter()
+	if err != nil {
+		return annotationState{}, err
+	}
+	annotations1, err1 := api.GetAnnotations(server1, filter)
+	annotations2, err2 := api.GetAnnotations(server2, filter)
+	dashdb1, err3 := api.GetDashboards(server1)
+	dashdb2, err4 := api.GetDashboards(server2)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return annotationState{}, err
+	}
+	byKey := func(annotations []api.Annotation, mapper *dashboardMapper) map[string]api.Annotation {
+		m := map[string]api.Annotation{}
+		for _, annotation := range annotations {
+			m[annotationKey(annotation, mapper)] = annotation
+		}
+		return m
+	}
+	return annotationState{
+		one:    byKey(annotations1, newDashboardMapper(dashdb1, dashdb1)),
+		two:    byKey(annotations2, newDashboardMapper(dashdb2, dashdb2)),
+		mapper: newDashboardMapper(dashdb1, dashdb2),
+	}, nil
+}
+
+func (as annotationState) diff() [][]string {
+	fields1 := map[string]map[string]string{}
+	fields2 := map[string]map[string]string{}
+	for key, annotation := range as.one {
+		fields1[key] = annotationFields(annotation)
+	}
+	for key, annotation := range as.two {
+		fields2[key] = annotationFields(annotation)
+	}
+	return diffNamed("Annotations", fields1, fields2)
+}
+
+func diffAnnotationsCmd() *cobra.Command {
+	opts := annotationOptions{}
+	cmd := &cobra.Command{
+		Use:   "annotations [server1 server2]",
+		Short: "diff annotations of two grafanas",
+		Long:  "Compare annotations within time range by time, text, dashboard and panel. Dashboards are compared by title",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			state, err := getAnnotationState(server1, server2, opts)
+			if err != nil {
+				return err
+			}
+			diff := state.diff()
+			if len(diff) == 0 {
+				slog.Info("annotations are identical", server1.Name, server2.Name)
+				return nil
+			}
+			renderTable([]string{"", server1.Name, server2.Name}, diff)
+			return exitStatus(cmd, ErrFound)
+		},
+	}
+	opts.addFlags(cmd)
+	return cmd
+}
+
+func pushAnnotationsCmd() *cobra.Command {
+	var dryRun bool
+	opts := annotationOptions{}
+	cmd := &cobra.Command{
+		Use:   "push-annotations [source target]",
+		Short: "copy annotations from one grafana to another",
+		Long: "Create annotations, which are missing from target within time range. " +
+			"Annotations of dashboards, which are missing from target, are skipped",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			source, err1 := getServer(ctx, args[0])
+			target, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			state, err := getAnnotationState(source, target, opts)
+			if err != nil {
+				return err
+			}
+			created, skipped := 0, 0
+			for _, key := range sortedKeys(state.one) {
+				if _, ok := state.two[key]; ok {
+					continue
+				}
+				annotation, ok := remapAnnotation(state.one[key], state.mapper)
+				if !ok {
+					slog.Warn("annotation skipped, dashboard missing from target", "annotation", key)
+					skipped++
+					continue
+				}
+				if !dryRun {
+					if err = api.CreateAnnotation(target, annotation); err != nil {
+						return fmt.Errorf("error in creating annotation %s: %w", key, err)
+					}
+				}
+				created++
+			}
+			slog.Info(pushAction(false, dryRun)+" annotations", "from", source.Name, "to", target.Name,
+				"count", created, "skipped", skipped)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show what would be pushed")
+	opts.addFlags(cmd)
+	return cmd
+}
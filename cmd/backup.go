@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/backup"
+)
+
+func addBackupFlag(cmd *cobra.Command, dir *string) {
+	cmd.Flags().StringVar(dir, "backup-dir", "", "directory for backups (default is $HOME/.grafana-dashboard-sync-backups)")
+}
+
+// newBackupSet creates backup set into dir, defaults.backup_dir from config or default location.
+func newBackupSet(ctx context.Context, dir string) (*backup.Set, error) {
+	cfg, err := config.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = cfg.Defaults.BackupDir
+	}
+	if dir == "" {
+		root, err := backup.DefaultRoot()
+		if err != nil {
+			return nil, err
+		}
+		dir = root
+	}
+	return backup.NewSet(dir), nil
+}
+
+// saveBackup stores current content of dashboard, as Grafana returned it, before it gets modified.
+func saveBackup(set *backup.Set, server config.Grafana, value *board) (string, error) {
+	body, err := value.json.Body()
+	if err != nil {
+		return "", fmt.Errorf("backup of %s failed: %w", value.db.Title, err)
+	}
+	path, err := set.Save(backup.Entry{
+		Server:  server.Name,
+		UID:     value.db.UID,
+		Title:   value.db.Title,
+		Version: value.json.Dashboard.Version,
+	}, body)
+	if err != nil {
+		return "", fmt.Errorf("backup of %s failed: %w", value.db.Title, err)
+	}
+	return path, nil
+}
+
+// saveRuleGroup stores current content of alert rule group before it gets replaced.
+func saveRuleGroup(set *backup.Set, server config.Grafana, group api.RuleGroup) (string, error) {
+	path, err := set.Save(backup.Entry{
+		Server: server.Name,
+		Kind:   backup.KindRuleGroup,
+		UID:    group.FolderUID + "-" + url.PathEscape(group.Title),
+		Title:  group.Title,
+	}, group)
+	if err != nil {
+		return "", fmt.Errorf("backup of rule group %s failed: %w", group.Title, err)
+	}
+	return path, nil
+}
+
+// restoreRuleGroup replaces rule group on server with saved one. Current group is backed up first.
+func restoreRuleGroup(set, current *backup.Set, server config.Grafana, entry backup.Entry) error {
+	group := api.RuleGroup{}
+	if err := set.Load(entry, &group); err != nil {
+		return err
+	}
+	value, err := api.GetRuleGroup(server, group.FolderUID, group.Title)
+	switch {
+	case err == nil:
+		if _, err = saveRuleGroup(current, server, value); err != nil {
+			return err
+		}
+	case !api.IsNotFound(err):
+		return err
+	}
+	return api.PutRuleGroup(server, group)
+}
+
+// putLibraryPanel takes backup of current library panel on target (if any) before overwriting it.
+func putLibraryPanel(set *backup.Set, target config.Grafana, current *api.LibraryPanel, panel api.LibraryPanel) error {
+	panel.Version = 0
+	if current != nil {
+		_, err := set.Save(backup.Entry{
+			Server:  target.Name,
+			Kind:    backup.KindLibraryPanel,
+			UID:     current.UID,
+			Title:   current.Name,
+			Version: current.Version,
+		}, current)
+		if err != nil {
+			return fmt.Errorf("backup of library panel %s failed: %w", current.Name, err)
+		}
+		panel.Version = current.Version
+	}
+	return api.PutLibraryPanel(target, panel)
+}
+
+// restoreLibraryPanel replaces library panel on server with saved one. Current panel is backed up first.
+func restoreLibraryPanel(set, current *backup.Set, server config.Grafana, entry backup.Entry) error {
+	panel := api.LibraryPanel{}
+	if err := set.Load(entry, &panel); err != nil {
+		return err
+	}
+	value, err := api.GetLibraryPanel(server, panel.UID)
+	switch {
+	case api.IsNotFound(err):
+		return putLibraryPanel(current, server, nil, panel)
+	case err != nil:
+		return err
+	}
+	return putLibraryPanel(current, server, &value, panel)
+}
+
+// putDashboard takes backup of current dashboard on target (if any) before overwriting it.
+func putDashboard(
+	set *backup.Set, target config.Grafana, current *board, dashboard api.DashboardJSON, message string,
+) (int, error) {
+	if current != nil {
+		if _, err := saveBackup(set, target, current); err != nil {
+			return 0, err
+		}
+	}
+	folderUID, err := targetFolder(target, dashboard.Meta.FolderUID, dashboard.Meta.FolderTitle)
+	if err != nil {
+		return 0, err
+	}
+	dashboard.Meta.FolderUID = folderUID
+	return api.PutDashboard(target, dashboard, message)
+}
+
+// targetFolder returns uid of folder on target, where dashboard from folder of source should be written.
+// Folder is matched by uid and then by title. Missing folder is created with uid and title from source.
+func targetFolder(target config.Grafana, uid, title string) (string, error) {
+	if uid == "" {
+		return "", nil
+	}
+	folders, err := api.GetFolders(target)
+	if err != nil {
+		return "", err
+	}
+	for _, folder := range folders {
+		if folder.UID == uid {
+			return uid, nil
+		}
+	}
+	for _, folder := range folders {
+		if title != "" && folder.Title == title {
+			return folder.UID, nil
+		}
+	}
+	if title == "" {
+		return "", fmt.Errorf("folder (%s) not found from %s", uid, target.Name)
+	}
+	folder, err := api.CreateFolder(target, api.Folder{UID: uid, Title: title})
+	if err != nil {
+		return "", fmt.Errorf("error in creating folder %s on %s: %w", title, target.Name, err)
+	}
+	slog.Info("folder created", "server", target.Name, "folder", title)
+	return folder.UID, nil
+}
+
+// currentBoard returns dashboard with given uid from server or nil, if it doesn't exist.
+func currentBoard(server config.Grafana, uid string) (*board, error) {
+	dashdbs, err := api.GetDashboards(server)
+	if err != nil {
+		return nil, err
+	}
+	for _, db := range dashdbs {
+		if db.UID != uid {
+			continue
+		}
+		dboard, err := db.GetJSON()
+		if err != nil {
+			return nil, err
+		}
+		return &board{db: db, json: dboard}, nil
+	}
+	return nil, nil
+}
+
+func restoreBackupCmd() *cobra.Command {
+	var backupDir string
+	cmd := &cobra.Command{
+		Use:   "restore-backup [backup-set] [server...]",
+		Short: "restore dashboards from backup set",
+		Long: "Upload dashboards, library panels and alert rule groups from backup set back to servers " +
+			"they were taken from. Restore can be limited to given servers",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			set, err := backup.Open(args[0])
+			if err != nil {
+				return err
+			}
+			current, err := newBackupSet(ctx, backupDir)
+			if err != nil {
+				return err
+			}
+			for _, entry := range set.Manifest.Entries {
+				if len(args) > 1 && !slices.Contains(args[1:], entry.Server) {
+					continue
+				}
+				server, err := getServer(ctx, entry.Server)
+				if err != nil {
+					return err
+				}
+				switch entry.Kind {
+				case backup.KindRuleGroup:
+					if err = restoreRuleGroup(set, current, server, entry); err != nil {
+						return err
+					}
+					slog.Info("rule group restored", "server", server.Name, "group", entry.Title)
+					continue
+				case backup.KindLibraryPanel:
+					if err = restoreLibraryPanel(set, current, server, entry); err != nil {
+						return err
+					}
+					slog.Info("library panel restored", "server", server.Name, "panel", entry.Title)
+					continue
+				}
+				dboard := api.DashboardJSON{}
+				if err = set.Load(entry, &dboard); err != nil {
+					return err
+				}
+				value, err := currentBoard(server, entry.UID)
+				if err != nil {
+					return err
+				}
+				message := fmt.Sprintf("restored from backup %s", set.Path)
+				if _, err = putDashboard(current, server, value, dboard, message); err != nil {
+					return err
+				}
+				slog.Info("dashboard restored", "server", server.Name, "dashboard", entry.Title, "version", entry.Version)
+			}
+			return nil
+		},
+	}
+	addBackupFlag(cmd, &backupDir)
+	return cmd
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+func tokenSource(server config.Grafana) string {
+	switch {
+	case server.BearerFile != "":
+		return "bearer_file"
+	case server.BearerCommand != "":
+		return "bearer_command"
+	}
+	return "bearer"
+}
+
+func configValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "validate config file",
+		Long:  "Check config file and resolve bearer tokens of all servers",
+		Args:  cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return loadConfig(cmd, true)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Get(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if err = cfg.Validate(); err != nil {
+				return err
+			}
+			names := []string{}
+			for name := range cfg.Servers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			rows := [][]string{}
+			failed := 0
+			for _, name := range names {
+				server := cfg.Servers[name]
+				status := "ok"
+				if resolved, err := server.Resolve(); err != nil {
+					status = err.Error()
+					failed++
+				} else if resolved.Bearer == "" {
+					status = "empty token"
+					failed++
+				}
+				rows = append(rows, []string{name, server.URL, tokenSource(server), status})
+			}
+			renderTable([]string{"Server", "URL", "Token from", "Status"}, rows)
+			if failed > 0 {
+				return fmt.Errorf("%d server(s) failed validation", failed)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func configShowCmd() *cobra.Command {
+	var redacted bool
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "show effective config",
+		Long:  "Print config after defaults and environment variables have been applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Get(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if redacted {
+				cfg = cfg.Redacted()
+			}
+			encoder := yaml.NewEncoder(os.Stdout)
+			encoder.SetIndent(2)
+			if err = encoder.Encode(cfg); err != nil {
+				return err
+			}
+			return encoder.Close()
+		},
+	}
+	cmd.Flags().BoolVar(&redacted, "redacted", false, "mask bearer tokens and secrets")
+	return cmd
+}
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "inspect configuration",
+	}
+	cmd.AddCommand(configShowCmd(), configValidateCmd())
+	return cmd
+}
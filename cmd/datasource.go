@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// dsSecrets collects secure fields that are set on source data source from target's config.
+// Fields, which are already set on target, are left out, because Grafana keeps them as they are.
+// It returns names of fields that are missing.
+func dsSecrets(target config.Grafana, ds api.DataSource, existing map[string]bool) (map[string]string, []string) {
+	secure := map[string]string{}
+	missing := []string{}
+	for field, isSet := range ds.SecureJSONFields {
+		if !isSet || existing[field] {
+			continue
+		}
+		value, ok := target.Secret(ds.Name, field)
+		if !ok {
+			missing = append(missing, field)
+			continue
+		}
+		secure[field] = value
+	}
+	sort.Strings(missing)
+	return secure, missing
+}
+
+// pushDatasource creates or updates data source on target.
+// It returns action taken and possible details about it.
+func pushDatasource(
+	source, target config.Grafana, value api.DataSource, existing *api.DataSource, dryRun bool,
+) (string, string, error) {
+	full, err := api.GetDataSource(source, value.UID)
+	if err != nil {
+		return "", "", err
+	}
+	var existingSecrets map[string]bool
+	if existing != nil {
+		current, err := api.GetDataSource(target, existing.UID)
+		if err != nil {
+			return "", "", err
+		}
+		full.Id = current.Id
+		full.UID = current.UID
+		full.Version = current.Version
+		existingSecrets = current.SecureJSONFields
+		if len(diffDatasource(full, current)) == 0 {
+			return "unchanged", "", nil
+		}
+		if current.ReadOnly {
+			return "skipped", "read-only on " + target.Name, nil
+		}
+	}
+	secure, missing := dsSecrets(target, full, existingSecrets)
+	if len(missing) > 0 {
+		return "skipped", "missing secrets: " + strings.Join(missing, ", "), nil
+	}
+	switch {
+	case dryRun && existing == nil:
+		return "would create", "", nil
+	case dryRun:
+		return "would update", "", nil
+	case existing == nil:
+		return "created", "", api.CreateDataSource(target, full, secure)
+	}
+	return "updated", "", api.UpdateDataSource(target, full, secure)
+}
+
+func pushDatasourcesCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "push-datasources [source target]",
+		Short: "copy data sources from one grafana to another",
+		Long: "Create or update data sources on target to match source. " +
+			"Secure fields, which aren't set on target yet, are read from target's secrets in config or from " +
+			"GDS_SECRET_<SERVER>_<DATASOURCE>_<FIELD> environment variables",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			source, err1 := getServer(ctx, args[0])
+			target, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			ds1, err1 := api.GetDataSources(source)
+			ds2, err2 := api.GetDataSources(target)
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			dsMap2 := dsToMap(ds2, cfg.Ignore)
+			rows := [][]string{}
+			for _, value := range ds1 {
+				if cfg.Ignore.DataSource(value.Name) {
+					continue
+				}
+				var existing *api.DataSource
+				if item, ok := dsMap2[value.Name]; ok {
+					existing = &item
+				}
+				action, details, err := pushDatasource(source, target, value, existing, dryRun)
+				if err != nil {
+					return err
+				}
+				if action == "skipped" {
+					slog.Warn("data source skipped", "datasource", value.Name, "reason", details)
+				}
+				rows = append(rows, []string{value.Name, action, details})
+			}
+			renderTable([]string{"Data source", "Action", "Details"}, rows)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show what would be pushed")
+	return cmd
+}
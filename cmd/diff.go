@@ -3,8 +3,12 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/olekukonko/tablewriter"
@@ -67,14 +71,80 @@ func diffVars(one, two []api.Variable) [][]string {
 	return diff
 }
 
+// describeVarRefs tells whether variable is defined on dashboard and where it is referenced.
+func describeVarRefs(dashboard api.DashboardJSON, refs []api.VariableRef, name string) string {
+	state := "undefined"
+	if _, ok := varsToMap(dashboard.Dashboard.Templating.List)[name]; ok {
+		state = "defined"
+	}
+	locations := []string{}
+	for _, ref := range refs {
+		if ref.Name == name && !slices.Contains(locations, ref.Location) {
+			locations = append(locations, ref.Location)
+		}
+	}
+	if len(locations) == 0 {
+		return state + ", not used"
+	}
+	return state + ", used in\n" + strings.Join(locations, "\n")
+}
+
+// diffVarRefs shows references of variables, which are defined only on one side.
+// References to variable, which is missing, break queries and links.
+func diffVarRefs(one, two api.DashboardJSON) [][]string {
+	oneVars := varsToMap(one.Dashboard.Templating.List)
+	twoVars := varsToMap(two.Dashboard.Templating.List)
+	names := []string{}
+	for name := range oneVars {
+		if _, ok := twoVars[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range twoVars {
+		if _, ok := oneVars[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	oneRefs := one.VariableRefs()
+	twoRefs := two.VariableRefs()
+	diff := [][]string{}
+	for _, name := range names {
+		diff = append(diff, []string{
+			name + " references", describeVarRefs(one, oneRefs, name), describeVarRefs(two, twoRefs, name),
+		})
+	}
+	return diff
+}
+
+// panelTitle is key for matching panels. Untitled panels are matched by id.
+func panelTitle(item api.Panel) string {
+	title := strings.TrimSpace(item.Title)
+	if title == "" {
+		return fmt.Sprintf("#%d", item.Id)
+	}
+	return title
+}
+
 func panelToMap(panels []api.Panel) map[string]panel {
 	m := map[string]panel{}
 	for idx, item := range panels {
-		m[strings.TrimSpace(item.Title)] = panel{index: idx, panel: item}
+		m[panelTitle(item)] = panel{index: idx, panel: item}
 	}
 	return m
 }
 
+// filterDashboards leaves out dashboards that are ignored in config.
+func filterDashboards(dashboards []api.Dashboard, ignore config.Ignore) []api.Dashboard {
+	ret := []api.Dashboard{}
+	for _, item := range dashboards {
+		if !ignore.Dashboard(item.Title, item.UID, item.FolderTitle, item.FolderUID) {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
 func dbToMap(dashboards []api.Dashboard) (map[string]board, error) {
 	m := map[string]board{}
 	for _, item := range dashboards {
@@ -134,6 +204,13 @@ func diffTargets(one, two []api.Target) [][]string {
 	return diff
 }
 
+func libraryPanelName(item api.Panel) string {
+	if item.LibraryPanel == nil {
+		return ""
+	}
+	return item.LibraryPanel.Name
+}
+
 func diffPanels(one, two []api.Panel) [][]string {
 	diff := [][]string{}
 	onePanels := panelToMap(one)
@@ -155,6 +232,9 @@ func diffPanels(one, two []api.Panel) [][]string {
 				fmt.Sprintf("#%d", panel2.index),
 			})
 		}
+		if lib1, lib2 := libraryPanelName(panel1.panel), libraryPanelName(panel2.panel); lib1 != lib2 {
+			diff = append(diff, []string{"Panel: " + panel1.panel.Title + "\nLibrary panel mismatch", lib1, lib2})
+		}
 		for _, row := range diffTargets(panel1.panel.Targets, panel2.panel.Targets) {
 			diff = append(diff, []string{"Panel: " + panel1.panel.Title + "\n" + row[0], row[1], row[2]})
 		}
@@ -171,24 +251,52 @@ func diffPanels(one, two []api.Panel) [][]string {
 	return diff
 }
 
-func diffDashboards(server1, server2 config.Grafana) error {
-	dashdb1, err1 := api.GetDashboards(server1)
-	dashdb2, err2 := api.GetDashboards(server2)
+// fetchBoards gets dashboards, which aren't ignored, from server and maps them by title.
+// Library panels are resolved, so that their content gets compared.
+func fetchBoards(server config.Grafana, ignore config.Ignore) (map[string]board, error) {
+	return fetchBoardsBy(server, ignore, dbToMap)
+}
+
+// fetchBoardsBy is fetchBoards with dashboards mapped by toMap.
+func fetchBoardsBy(
+	server config.Grafana, ignore config.Ignore, toMap func([]api.Dashboard) (map[string]board, error),
+) (map[string]board, error) {
+	dashboards, err := api.GetDashboards(server)
+	if err != nil {
+		return nil, err
+	}
+	library, err := fetchLibrary(server)
+	if err != nil {
+		return nil, err
+	}
+	boards, err := toMap(filterDashboards(dashboards, ignore))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range boards {
+		if value.json, err = value.json.ResolveLibraryPanels(library); err != nil {
+			return nil, err
+		}
+		boards[key] = value
+	}
+	return boards, nil
+}
+
+// diffDashboards compares dashboards of two servers. Dashboards of server1 are
+// rewritten for server2 first, so that expected mappings and transforms aren't reported.
+func diffDashboards(server1, server2 config.Grafana, ignore config.Ignore, mappings config.Mappings) error {
+	dbMap1, err1 := fetchBoards(server1, ignore)
+	dbMap2, err2 := fetchBoards(server2, ignore)
 	uniqOne := []string{}
 	uniqTwo := []string{}
 	if err := errors.Join(err1, err2); err != nil {
 		return err
 	}
 	identical := true
-	if len(dashdb1) != len(dashdb2) {
-		slog.Warn("Different number of dashboards", server1.Name, len(dashdb1), server2.Name, len(dashdb2))
+	if len(dbMap1) != len(dbMap2) {
+		slog.Warn("Different number of dashboards", server1.Name, len(dbMap1), server2.Name, len(dbMap2))
 		identical = false
 	}
-	dbMap1, err1 := dbToMap(dashdb1)
-	dbMap2, err2 := dbToMap(dashdb2)
-	if err := errors.Join(err1, err2); err != nil {
-		return err
-	}
 	diff := [][]string{}
 	for key, value1 := range dbMap1 {
 		value2, ok := dbMap2[key]
@@ -198,12 +306,11 @@ func diffDashboards(server1, server2 config.Grafana) error {
 			identical = false
 			continue
 		}
-		oneDiff := diffVars(value1.json.Dashboard.Templating.List, value2.json.Dashboard.Templating.List)
-		for _, item := range oneDiff {
-			diff = append(diff, []string{value1.db.Title + "\n" + item[0], item[1], item[2]})
+		rewritten, err := rewriteDashboard(mappings, server1.Name, server2.Name, value1.json)
+		if err != nil {
+			return err
 		}
-		oneDiff = diffPanels(value1.json.Flatten(), value2.json.Flatten())
-		for _, item := range oneDiff {
+		for _, item := range diffDashboardJSON(rewritten, value2.json) {
 			diff = append(diff, []string{value1.db.Title + "\n" + item[0], item[1], item[2]})
 		}
 		delete(dbMap1, key)
@@ -217,93 +324,277 @@ func diffDashboards(server1, server2 config.Grafana) error {
 		slog.Info("dashboards are identical", server1.Name, server2.Name)
 		return nil
 	}
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"", server1.Name, server2.Name})
+	diff = append([][]string{
+		{"Unique Dashboards", strings.Join(uniqOne, "\n"), strings.Join(uniqTwo, "\n")},
+	}, diff...)
+	renderTable([]string{"", server1.Name, server2.Name}, diff)
+	return ErrFound
+}
+
+// matrixRow compares dashboard on every server against reference, which is
+// the first server that has the dashboard. Reference is rewritten for each server.
+func matrixRow(
+	title string, servers []config.Grafana, boards []map[string]board, mappings config.Mappings,
+) ([]string, bool, error) {
+	row := []string{title}
+	identical := true
+	var reference *board
+	var source string
+	for idx, dbMap := range boards {
+		value, ok := dbMap[title]
+		switch {
+		case !ok:
+			row = append(row, "missing")
+			identical = false
+		case reference == nil:
+			reference = &value
+			source = servers[idx].Name
+			row = append(row, "reference")
+		default:
+			rewritten, err := rewriteDashboard(mappings, source, servers[idx].Name, reference.json)
+			if err != nil {
+				return row, false, err
+			}
+			if diff := diffDashboardJSON(rewritten, value.json); len(diff) > 0 {
+				row = append(row, fmt.Sprintf("differs (%d)", len(diff)))
+				identical = false
+			} else {
+				row = append(row, "match")
+			}
+		}
+	}
+	return row, identical, nil
+}
+
+// diffMatrix compares dashboards across all given servers.
+func diffMatrix(servers []config.Grafana, ignore config.Ignore, mappings config.Mappings) error {
+	boards := []map[string]board{}
+	header := []string{"Dashboard"}
+	titles := []string{}
+	for _, server := range servers {
+		dbMap, err := fetchBoards(server, ignore)
+		if err != nil {
+			return err
+		}
+		for title := range dbMap {
+			if !slices.Contains(titles, title) {
+				titles = append(titles, title)
+			}
+		}
+		boards = append(boards, dbMap)
+		header = append(header, server.Name)
+	}
+	sort.Strings(titles)
+	rows := [][]string{}
+	for _, title := range titles {
+		row, identical, err := matrixRow(title, servers, boards, mappings)
+		if err != nil {
+			return err
+		}
+		if !identical {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		slog.Info("dashboards are identical", "servers", header[1:])
+		return nil
+	}
+	renderTable(header, rows)
+	return ErrFound
+}
+
+// diffDashboardJSON compares variables and panels of two dashboards.
+func diffDashboardJSON(one, two api.DashboardJSON) [][]string {
+	diff := diffVars(one.Dashboard.Templating.List, two.Dashboard.Templating.List)
+	diff = append(diff, diffVarRefs(one, two)...)
+	return append(diff, diffPanels(one.Flatten(), two.Flatten())...)
+}
+
+func renderTable(header []string, rows [][]string) {
+	renderTableTo(os.Stdout, header, rows)
+}
+
+func renderTableTo(w io.Writer, header []string, rows [][]string) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
 	table.SetReflowDuringAutoWrap(false)
 	table.SetAutoWrapText(false)
 	table.SetRowLine(true)
-	table.Append([]string{"Unique Dashboards", strings.Join(uniqOne, "\n"), strings.Join(uniqTwo, "\n")})
-	table.AppendBulk(diff)
+	table.AppendBulk(rows)
 	table.Render()
-	return nil
 }
 
-func dsToMap(ds []api.DataSource) map[string]api.DataSource {
+// dsToMap maps data sources by name. Data sources ignored in config are left out.
+func dsToMap(ds []api.DataSource, ignore config.Ignore) map[string]api.DataSource {
 	m := map[string]api.DataSource{}
 	for _, item := range ds {
-		m[item.Name] = item
+		if !ignore.DataSource(item.Name) {
+			m[item.Name] = item
+		}
 	}
 	return m
 }
 
-func diffDatasources(server1, server2 config.Grafana) error {
+// dsFields flattens comparable fields of data source into map.
+// Server specific fields (id, uid, orgId and version) are left out.
+// Data sources are matched by name and their uids usually differ between servers.
+func dsFields(ds api.DataSource) map[string]string {
+	fields := map[string]string{
+		"type":            ds.Type,
+		"access":          ds.Access,
+		"url":             ds.URL,
+		"user":            ds.User,
+		"database":        ds.Database,
+		"basicAuth":       strconv.FormatBool(ds.BasicAuth),
+		"basicAuthUser":   ds.BasicAuthUser,
+		"withCredentials": strconv.FormatBool(ds.WithCredentials),
+		"isDefault":       strconv.FormatBool(ds.IsDefault),
+		"readOnly":        strconv.FormatBool(ds.ReadOnly),
+	}
+	flattenJSON("jsonData", ds.JSONData, fields)
+	for key, value := range ds.SecureJSONFields {
+		fields["secureJsonFields."+key] = strconv.FormatBool(value)
+	}
+	return fields
+}
+
+// diffDatasource compares two data sources field by field.
+func diffDatasource(one, two api.DataSource) [][]string {
+	return diffFields(dsFields(one), dsFields(two))
+}
+
+// diffFields compares flattened fields and returns mismatches sorted by field name.
+// Values are compared in full and truncated only for the table.
+func diffFields(oneFields, twoFields map[string]string) [][]string {
+	keys := []string{}
+	for key := range oneFields {
+		keys = append(keys, key)
+	}
+	for key := range twoFields {
+		if _, ok := oneFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	diff := [][]string{}
+	for _, key := range keys {
+		if oneFields[key] != twoFields[key] {
+			diff = append(diff, []string{key + " mismatch", truncLine(oneFields[key]), truncLine(twoFields[key])})
+		}
+	}
+	return diff
+}
+
+func diffDatasources(server1, server2 config.Grafana, ignore config.Ignore) error {
 	ds1, err1 := api.GetDataSources(server1)
 	ds2, err2 := api.GetDataSources(server2)
 	if err := errors.Join(err1, err2); err != nil {
 		return err
 	}
-	identical := true
-	if len(ds1) != len(ds2) {
-		slog.Warn("Different number of data sources", server1.Name, len(ds1), server2.Name, len(ds2))
-		identical = false
-	}
-	dsMap1 := dsToMap(ds1)
-	dsMap2 := dsToMap(ds2)
+	uniqOne := []string{}
+	uniqTwo := []string{}
+	dsMap1 := dsToMap(ds1, ignore)
+	dsMap2 := dsToMap(ds2, ignore)
+	diff := [][]string{}
 	for key, value1 := range dsMap1 {
 		value2, ok := dsMap2[key]
 		if !ok {
-			slog.Warn("only in "+server1.Name, "datasource", key)
+			uniqOne = append(uniqOne, key)
 			delete(dsMap1, key)
-			identical = false
 			continue
 		}
-		if value1.Type != value2.Type {
-			slog.Warn("type mismatch", server1.Name, value1.Type, server2.Name, value2.Type)
-			identical = false
+		full1, err1 := api.GetDataSource(server1, value1.UID)
+		full2, err2 := api.GetDataSource(server2, value2.UID)
+		if err := errors.Join(err1, err2); err != nil {
+			return err
+		}
+		for _, item := range diffDatasource(full1, full2) {
+			diff = append(diff, []string{key + "\n" + item[0], item[1], item[2]})
 		}
 		delete(dsMap1, key)
 		delete(dsMap2, key)
 	}
 	for key := range dsMap2 {
-		slog.Warn("only in "+server2.Name, "datasource", key)
-		identical = false
+		uniqTwo = append(uniqTwo, key)
 	}
-	if identical {
+	if len(uniqOne) == 0 && len(uniqTwo) == 0 && len(diff) == 0 {
 		slog.Info("data sources are identical", server1.Name, server2.Name)
+		return nil
 	}
-	return nil
+	sort.Strings(uniqOne)
+	sort.Strings(uniqTwo)
+	sort.Slice(diff, func(i, j int) bool { return diff[i][0] < diff[j][0] })
+	diff = append([][]string{
+		{"Unique Data Sources", strings.Join(uniqOne, "\n"), strings.Join(uniqTwo, "\n")},
+	}, diff...)
+	renderTable([]string{"", server1.Name, server2.Name}, diff)
+	return ErrFound
 }
 
 func diffCmd() *cobra.Command {
+	var all, permissions bool
 	cmd := &cobra.Command{
 		Use:   "diff [server1 server2]",
 		Short: "diff two grafanas configuration",
-		Long:  "Fetch configuration from two servers and create diff",
-		Args:  cobra.MinimumNArgs(2),
+		Long: "Fetch configuration from two servers and create diff. " +
+			"With --all, compare dashboards of all servers in pipeline order against the first one",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if all {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(2)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 			cfg, err := config.Get(ctx)
 			if err != nil {
 				return err
 			}
-			server1, ok := cfg[args[0]]
-			if !ok {
-				slog.Error("Server not found from config", "server1", server1)
+			if all {
+				servers := []config.Grafana{}
+				for _, name := range cfg.ServerNames() {
+					server, err := getServer(ctx, name)
+					if err != nil {
+						return err
+					}
+					servers = append(servers, server)
+				}
+				return exitStatus(cmd, diffMatrix(servers, cfg.Ignore, cfg.Mappings))
 			}
-			server2, ok := cfg[args[1]]
-			if !ok {
-				slog.Error("Server not found from config", "server2", server2)
-			}
-			err = diffDatasources(server1, server2)
-			if err != nil {
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
 				return err
 			}
-			err = diffDashboards(server1, server2)
-			if err != nil {
-				return err
+			steps := []func() error{
+				func() error { return diffDatasources(server1, server2, cfg.Ignore) },
+				func() error { return diffDashboards(server1, server2, cfg.Ignore, cfg.Mappings) },
+			}
+			if permissions {
+				steps = append(steps, func() error { return diffPermissions(server1, server2, cfg.Ignore) })
+			}
+			var found error
+			for _, step := range steps {
+				if err = step(); errors.Is(err, ErrFound) {
+					found = err
+				} else if err != nil {
+					return err
+				}
 			}
-			return nil
+			return exitStatus(cmd, found)
 		},
 	}
+	cmd.PersistentFlags().Bool("exit-code", false, "exit with 2, when differences are found")
+	cmd.Flags().BoolVar(&all, "all", false, "compare dashboards of all servers")
+	cmd.Flags().BoolVar(&permissions, "permissions", false, "compare permissions of dashboards and folders")
+	cmd.AddCommand(
+		diffAlertingCmd(),
+		diffAlertsCmd(),
+		diffAnnotationsCmd(),
+		diffIdentitiesCmd(),
+		diffLibraryPanelsCmd(),
+		diffPlaylistsCmd(),
+		diffPluginsCmd(),
+	)
 	return cmd
 }
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
 )
 
 // {
@@ -70,3 +72,96 @@ func TestDiffTargets(t *testing.T) {
 		t.Errorf("returned wrong refIds")
 	}
 }
+
+func TestDiffDatasource(t *testing.T) {
+	one := api.DataSource{
+		Id: 1, Name: "Prometheus", Type: "prometheus", UID: "prom", URL: "http://prometheus:9090",
+		JSONData:         map[string]interface{}{"httpMethod": "POST", "timeInterval": "30s"},
+		SecureJSONFields: map[string]bool{"basicAuthPassword": true},
+	}
+	two := one
+	two.Id = 2
+	two.UID = "prod-prom"
+	diff := diffDatasource(one, two)
+	if len(diff) != 0 {
+		t.Errorf("identical data sources returned %#v", diff)
+	}
+	two.URL = "http://prometheus.prod:9090"
+	two.JSONData = map[string]interface{}{"httpMethod": "GET", "timeInterval": "30s"}
+	two.SecureJSONFields = nil
+	diff = diffDatasource(one, two)
+	if len(diff) != 3 {
+		t.Fatalf("different data sources returned wrong number of lines: %#v", diff)
+	}
+	if diff[0][0] != "jsonData.httpMethod mismatch" || diff[1][2] != "" || diff[2][2] != two.URL {
+		t.Errorf("returned wrong lines: %#v", diff)
+	}
+	long := strings.Repeat("x", 70)
+	one.JSONData = map[string]interface{}{"customQueryParameters": map[string]interface{}{"q": long + "10"}}
+	two = one
+	two.JSONData = map[string]interface{}{"customQueryParameters": map[string]interface{}{"q": long + "99"}}
+	if diff = diffDatasource(one, two); len(diff) != 1 || diff[0][0] != "jsonData.customQueryParameters.q mismatch" {
+		t.Errorf("difference after truncation point wasn't found: %#v", diff)
+	}
+}
+
+func TestDsSecrets(t *testing.T) {
+	target := config.Grafana{Name: "prod", Secrets: map[string]map[string]string{"loki": {"password": "s3cret"}}}
+	ds := api.DataSource{Name: "Loki", SecureJSONFields: map[string]bool{"password": true, "token": true}}
+	if _, missing := dsSecrets(target, ds, nil); len(missing) != 1 || missing[0] != "token" {
+		t.Errorf("dsSecrets returned missing %v for new data source", missing)
+	}
+	secure, missing := dsSecrets(target, ds, map[string]bool{"token": true, "password": true})
+	if len(secure) != 0 || len(missing) != 0 {
+		t.Errorf("dsSecrets returned %v, %v for secrets already set on target", secure, missing)
+	}
+}
+
+func TestMatrixRow(t *testing.T) {
+	failed := api.Panel{Id: 1, Title: "Failed", Type: "stat", Targets: []api.Target{targetFailed}}
+	running := api.Panel{Id: 2, Title: "Running", Type: "stat", Targets: []api.Target{targetRunning}}
+	dev := map[string]board{"Kubernetes": {json: testDashboard(failed)}}
+	staging := map[string]board{"Kubernetes": {json: testDashboard(failed)}}
+	prod := map[string]board{"Kubernetes": {json: testDashboard(failed, running)}}
+	servers := []config.Grafana{{Name: "local"}, {Name: "dev"}, {Name: "staging"}, {Name: "prod"}}
+	row, identical, err := matrixRow("Kubernetes", servers, []map[string]board{{}, dev, staging, prod}, config.Mappings{})
+	if err != nil || identical {
+		t.Errorf("matrixRow should find differences")
+	}
+	expecting := []string{"Kubernetes", "missing", "reference", "match", "differs (1)"}
+	if strings.Join(row, ",") != strings.Join(expecting, ",") {
+		t.Errorf("matrixRow returned %v instead of %v", row, expecting)
+	}
+	if _, identical, _ = matrixRow("Kubernetes", servers, []map[string]board{dev, staging}, config.Mappings{}); !identical {
+		t.Errorf("matrixRow should match identical dashboards")
+	}
+	mappings := config.Mappings{Transforms: map[string][]config.Rule{
+		"staging": {{Path: "title", Set: "Staging"}},
+	}}
+	if _, identical, _ = matrixRow("Kubernetes", servers, []map[string]board{dev, staging}, mappings); !identical {
+		t.Errorf("matrixRow should ignore transform of title")
+	}
+}
+
+func TestDiffVarRefs(t *testing.T) {
+	failed := api.Panel{Id: 1, Title: "Failed", Type: "stat", Targets: []api.Target{targetFailed}}
+	one := testDashboard(failed)
+	two := testDashboard(failed)
+	two.Dashboard.Templating.List = []api.Variable{{Name: "namespace", Definition: "label_values(namespace)"}}
+	diff := diffVarRefs(one, two)
+	expecting := [][]string{
+		{"cluster references", "defined, used in\npanel Failed target A", "undefined, used in\npanel Failed target A"},
+		{"namespace references", "undefined, not used", "defined, not used"},
+	}
+	if len(diff) != len(expecting) {
+		t.Fatalf("diffVarRefs returned %v", diff)
+	}
+	for idx, row := range expecting {
+		if strings.Join(diff[idx], "|") != strings.Join(row, "|") {
+			t.Errorf("diffVarRefs returned %q instead of %q", diff[idx], row)
+		}
+	}
+	if diff = diffVarRefs(one, one); len(diff) != 0 {
+		t.Errorf("diffVarRefs of same dashboard returned %v", diff)
+	}
+}
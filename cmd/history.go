@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/backup"
+)
+
+func findVersion(versions []api.DashboardVersion, number int) (api.DashboardVersion, error) {
+	for _, item := range versions {
+		if item.Version == number {
+			return item, nil
+		}
+	}
+	return api.DashboardVersion{}, fmt.Errorf("version (%d) not found", number)
+}
+
+func parseVersion(versions []api.DashboardVersion, arg string) (api.DashboardVersion, error) {
+	number, err := strconv.Atoi(arg)
+	if err != nil {
+		return api.DashboardVersion{}, fmt.Errorf("version (%s) is not a number", arg)
+	}
+	return findVersion(versions, number)
+}
+
+// getVersionJSON fetches dashboard content as it was in given version number.
+func getVersionJSON(board api.Dashboard, number int) (api.DashboardJSON, error) {
+	versions, err := board.GetVersions()
+	if err != nil {
+		return api.DashboardJSON{}, err
+	}
+	version, err := findVersion(versions, number)
+	if err != nil {
+		return api.DashboardJSON{}, err
+	}
+	return board.GetVersion(version)
+}
+
+func diffVersions(board api.Dashboard, versions []api.DashboardVersion, arg1, arg2 string) error {
+	version1, err1 := parseVersion(versions, arg1)
+	version2, err2 := parseVersion(versions, arg2)
+	if err := errors.Join(err1, err2); err != nil {
+		return err
+	}
+	json1, err1 := board.GetVersion(version1)
+	json2, err2 := board.GetVersion(version2)
+	if err := errors.Join(err1, err2); err != nil {
+		return err
+	}
+	diff := diffDashboardJSON(json1, json2)
+	if len(diff) == 0 {
+		slog.Info("versions are identical", "version1", version1.Version, "version2", version2.Version)
+		return nil
+	}
+	renderTable([]string{board.Title, "version " + arg1, "version " + arg2}, diff)
+	return nil
+}
+
+func historyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history [server dashboard] [version1 version2]",
+		Short: "list or diff dashboard versions",
+		Long:  "List stored versions of dashboard or show diff between two of them",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 && len(args) != 4 {
+				return fmt.Errorf("accepts 2 or 4 arg(s), received %d", len(args))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server, err := getServer(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			board, err := api.FindDashboard(server, args[1])
+			if err != nil {
+				return err
+			}
+			versions, err := board.GetVersions()
+			if err != nil {
+				return err
+			}
+			if len(args) == 4 {
+				return diffVersions(board, versions, args[2], args[3])
+			}
+			rows := [][]string{}
+			for _, item := range versions {
+				rows = append(rows, []string{
+					strconv.Itoa(item.Version), item.Created, item.CreatedBy, item.Message,
+				})
+			}
+			renderTable([]string{"Version", "Created", "Created by", "Message"}, rows)
+			return nil
+		},
+	}
+	return cmd
+}
+
+// restoreVersion backs up current content of dashboard and rolls it back to given version.
+func restoreVersion(set *backup.Set, server config.Grafana, dboard api.Dashboard, version int) error {
+	current, err := dboard.GetJSON()
+	if err != nil {
+		return err
+	}
+	if _, err = saveBackup(set, server, &board{db: dboard, json: current}); err != nil {
+		return err
+	}
+	return dboard.Restore(version)
+}
+
+func restoreCmd() *cobra.Command {
+	var backupDir string
+	cmd := &cobra.Command{
+		Use:   "restore [server dashboard version]",
+		Short: "restore dashboard version",
+		Long:  "Roll dashboard back to earlier version",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server, err := getServer(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			dboard, err := api.FindDashboard(server, args[1])
+			if err != nil {
+				return err
+			}
+			versions, err := dboard.GetVersions()
+			if err != nil {
+				return err
+			}
+			version, err := parseVersion(versions, args[2])
+			if err != nil {
+				return err
+			}
+			set, err := newBackupSet(cmd.Context(), backupDir)
+			if err != nil {
+				return err
+			}
+			if err = restoreVersion(set, server, dboard, version.Version); err != nil {
+				return err
+			}
+			slog.Info("dashboard restored", "server", server.Name, "dashboard", dboard.Title, "version", version.Version)
+			return nil
+		},
+	}
+	addBackupFlag(cmd, &backupDir)
+	return cmd
+}
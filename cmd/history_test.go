@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/backup"
+)
+
+func TestRestoreVersion(t *testing.T) {
+	set := backup.NewSet(t.TempDir())
+	restored := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/search":
+			_, _ = w.Write([]byte(`[{"uid": "abc", "title": "App"}]`))
+		case "/api/dashboards/uid/abc":
+			_, _ = w.Write([]byte(`{"meta": {}, "dashboard": {"uid": "abc", "title": "App", "version": 5}}`))
+		case "/api/dashboards/uid/abc/restore":
+			if len(set.Manifest.Entries) != 1 {
+				t.Errorf("dashboard was restored before backup")
+			}
+			restored = true
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	grafana := config.Grafana{Name: "prod", URL: server.URL}
+	dboard, err := api.FindDashboard(grafana, "App")
+	if err != nil {
+		t.Fatalf("FindDashboard failed due to %v", err)
+	}
+	if err = restoreVersion(set, grafana, dboard, 3); err != nil || !restored {
+		t.Fatalf("restoreVersion returned %v (restored: %v)", err, restored)
+	}
+	if entry := set.Manifest.Entries[0]; entry.UID != "abc" || entry.Version != 5 {
+		t.Errorf("wrong backup entry %#v", entry)
+	}
+}
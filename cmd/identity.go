@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// identities are teams with members, org users and service accounts of grafana
+// flattened for diffNamed.
+type identities struct {
+	teams           map[string]map[string]string
+	users           map[string]map[string]string
+	serviceAccounts map[string]map[string]string
+}
+
+func memberRole(member api.TeamMember) string {
+	if member.Permission == 4 {
+		return "admin"
+	}
+	return "member"
+}
+
+func getIdentities(server config.Grafana) (identities, error) {
+	teams, err1 := api.GetTeams(server)
+	users, err2 := api.GetOrgUsers(server)
+	accounts, err3 := api.GetServiceAccounts(server)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return identities{}, err
+	}
+	ret := identities{
+		teams:           map[string]map[string]string{},
+		users:           map[string]map[string]string{},
+		serviceAccounts: map[string]map[string]string{},
+	}
+	for _, team := range teams {
+		members, err := api.GetTeamMembers(server, team.Id)
+		if err != nil {
+			return ret, err
+		}
+		ret.teams[team.Name] = teamFields(team, members)
+	}
+	for _, user := range users {
+		ret.users[user.Login] = map[string]string{"role": user.Role, "email": user.Email}
+	}
+	for _, account := range accounts {
+		ret.serviceAccounts[account.Name] = map[string]string{"role": account.Role, "disabled": describe(account.IsDisabled)}
+	}
+	return ret, nil
+}
+
+func teamFields(team api.Team, members []api.TeamMember) map[string]string {
+	fields := map[string]string{"email": team.Email}
+	for _, member := range members {
+		fields["member "+member.Login] = memberRole(member)
+	}
+	return fields
+}
+
+// fillMembers marks members, which are only on the other server, as (none).
+func fillMembers(one, two map[string]map[string]string) {
+	for name, fields := range one {
+		other, ok := two[name]
+		if !ok {
+			continue
+		}
+		for key := range fields {
+			if _, ok := other[key]; !ok {
+				other[key] = "(none)"
+			}
+		}
+		for key := range other {
+			if _, ok := fields[key]; !ok {
+				fields[key] = "(none)"
+			}
+		}
+	}
+}
+
+func diffIdentityList(one, two identities) [][]string {
+	fillMembers(one.teams, two.teams)
+	diff := diffNamed("Teams", one.teams, two.teams)
+	diff = append(diff, diffNamed("Users", one.users, two.users)...)
+	return append(diff, diffNamed("Service Accounts", one.serviceAccounts, two.serviceAccounts)...)
+}
+
+func diffIdentitiesCmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "identities [server1 server2]",
+		Short: "diff teams, team members, users and service accounts",
+		Long:  "Compare teams with their members, org users and service accounts by name, including roles",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			one, err1 := getIdentities(server1)
+			two, err2 := getIdentities(server2)
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			diff := diffIdentityList(one, two)
+			if len(diff) == 0 && (format == "table" || format == "") {
+				slog.Info("identities are identical", server1.Name, server2.Name)
+				return nil
+			}
+			if err := diffListing(server1.Name, server2.Name, diff).print(format); err != nil {
+				return err
+			}
+			return exitStatus(cmd, ErrFound)
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format: table, json or csv")
+	return cmd
+}
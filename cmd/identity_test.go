@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+func TestDiffIdentityList(t *testing.T) {
+	ops := api.Team{Id: 1, Name: "ops"}
+	one := identities{
+		teams: map[string]map[string]string{
+			"ops": teamFields(ops, []api.TeamMember{{Login: "alice", Permission: 4}, {Login: "bob"}}),
+			"dev": teamFields(api.Team{Id: 2, Name: "dev"}, nil),
+		},
+		users:           map[string]map[string]string{"alice": {"role": "Admin", "email": "alice@example.com"}},
+		serviceAccounts: map[string]map[string]string{},
+	}
+	two := identities{
+		teams: map[string]map[string]string{
+			"ops": teamFields(ops, []api.TeamMember{{Login: "alice"}, {Login: "carol"}}),
+		},
+		users:           map[string]map[string]string{"alice": {"role": "Viewer", "email": "alice@example.com"}},
+		serviceAccounts: map[string]map[string]string{"ci": {"role": "Editor"}},
+	}
+	diff := diffIdentityList(one, two)
+	expecting := [][]string{
+		{"Unique Teams", "dev", ""},
+		{"ops\nmember alice mismatch", "admin", "member"},
+		{"ops\nmember bob mismatch", "member", "(none)"},
+		{"ops\nmember carol mismatch", "(none)", "member"},
+		{"alice\nrole mismatch", "Admin", "Viewer"},
+		{"Unique Service Accounts", "", "ci"},
+	}
+	if !reflect.DeepEqual(diff, expecting) {
+		t.Errorf("diffIdentityList returned %#v", diff)
+	}
+}
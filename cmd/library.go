@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/backup"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/baseline"
+)
+
+// fetchLibrary gets library panels of server by uid.
+func fetchLibrary(server config.Grafana) (map[string]api.LibraryPanel, error) {
+	panels, err := api.GetLibraryPanels(server)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]api.LibraryPanel{}
+	for _, panel := range panels {
+		m[panel.UID] = panel
+	}
+	return m, nil
+}
+
+// libraryFields flattens comparable fields of library panel.
+// Server specific fields (id and version) are left out.
+func libraryFields(panel api.LibraryPanel) map[string]string {
+	fields := map[string]string{
+		"uid":         panel.UID,
+		"name":        panel.Name,
+		"type":        panel.Type,
+		"description": panel.Description,
+		"folderUid":   panel.FolderUID,
+	}
+	model := map[string]interface{}{}
+	for key, value := range panel.Model {
+		if key != "id" && key != "gridPos" && key != "libraryPanel" {
+			model[key] = value
+		}
+	}
+	flattenJSON("model", model, fields)
+	return fields
+}
+
+// libraryByName flattens library panels by name. Panels are mapped with mapping first.
+func libraryByName(library map[string]api.LibraryPanel, mapping map[string]string) (map[string]map[string]string, error) {
+	m := map[string]map[string]string{}
+	for _, panel := range library {
+		mapped, err := panel.MapDataSources(mapping)
+		if err != nil {
+			return nil, err
+		}
+		m[panel.Name] = libraryFields(mapped)
+	}
+	return m, nil
+}
+
+func diffLibraryPanels(server1, server2 config.Grafana, mappings config.Mappings) error {
+	library1, err1 := fetchLibrary(server1)
+	library2, err2 := fetchLibrary(server2)
+	if err := errors.Join(err1, err2); err != nil {
+		return err
+	}
+	fields1, err1 := libraryByName(library1, mappings.DataSourcesBetween(server1.Name, server2.Name))
+	fields2, err2 := libraryByName(library2, nil)
+	if err := errors.Join(err1, err2); err != nil {
+		return err
+	}
+	diff := diffNamed("Library Panels", fields1, fields2)
+	if len(diff) == 0 {
+		slog.Info("library panels are identical", server1.Name, server2.Name)
+		return nil
+	}
+	renderTable([]string{"", server1.Name, server2.Name}, diff)
+	return ErrFound
+}
+
+func diffLibraryPanelsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "library-panels [server1 server2]",
+		Short: "diff library panels of two grafanas",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			return exitStatus(cmd, diffLibraryPanels(server1, server2, cfg.Mappings))
+		},
+	}
+	return cmd
+}
+
+// pushLibraryPanels creates library panels used by dashboard on target, so that dashboard can be
+// written there. With update, library panels, which differ on target, are overwritten too.
+func pushLibraryPanels(
+	set *backup.Set, source, target config.Grafana, dashboard api.DashboardJSON, mappings config.Mappings, update bool,
+) error {
+	mapping := mappings.DataSourcesBetween(source.Name, target.Name)
+	for _, uid := range dashboard.LibraryPanelUIDs() {
+		panel, err := api.GetLibraryPanel(source, uid)
+		if err != nil {
+			return err
+		}
+		mapped, err := panel.MapDataSources(mapping)
+		if err != nil {
+			return err
+		}
+		current, err := api.GetLibraryPanel(target, uid)
+		switch {
+		case api.IsNotFound(err):
+			err = putLibraryPanel(set, target, nil, mapped)
+		case err != nil:
+			return err
+		case !update || len(diffFields(libraryFields(mapped), libraryFields(current))) == 0:
+			continue
+		default:
+			err = putLibraryPanel(set, target, &current, mapped)
+		}
+		if err != nil {
+			return err
+		}
+		slog.Info("library panel synced", "from", source.Name, "to", target.Name, "panel", panel.Name)
+	}
+	return nil
+}
+
+// libraryHash returns checksum of comparable fields of library panel.
+func libraryHash(panel api.LibraryPanel) (string, error) {
+	data, err := json.Marshal(libraryFields(panel))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// libraryKey keeps library panels apart from dashboards in baseline.
+func libraryKey(uid string) string {
+	return "library-panel/" + uid
+}
+
+// copyLibraryPanel maps library panel from source to target and writes it there.
+// It returns state of library panel on target.
+func copyLibraryPanel(
+	opts syncOptions, target, source config.Grafana, current *api.LibraryPanel, panel api.LibraryPanel,
+) (baseline.Entry, error) {
+	mapped, err := panel.MapDataSources(opts.mappings.DataSourcesBetween(source.Name, target.Name))
+	if err != nil {
+		return baseline.Entry{}, err
+	}
+	hash, err := libraryHash(mapped)
+	if err != nil {
+		return baseline.Entry{}, err
+	}
+	if err = putLibraryPanel(opts.backup, target, current, mapped); err != nil {
+		return baseline.Entry{}, err
+	}
+	slog.Info("library panel synced", "from", source.Name, "to", target.Name, "panel", panel.Name)
+	return baseline.Entry{Hash: hash}, nil
+}
+
+// syncLibraryPanels compares library panels against state from last sync and copies
+// the ones that have changed only on one server. It returns rows for sync report.
+func syncLibraryPanels(server1, server2 config.Grafana, state *baseline.State, opts syncOptions) ([][]string, error) {
+	library1, err1 := fetchLibrary(server1)
+	library2, err2 := fetchLibrary(server2)
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, err
+	}
+	uids := []string{}
+	for uid := range library1 {
+		uids = append(uids, uid)
+	}
+	for uid := range library2 {
+		if _, ok := library1[uid]; !ok {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Strings(uids)
+	hash := func(library map[string]api.LibraryPanel, uid string) (*api.LibraryPanel, string, error) {
+		panel, ok := library[uid]
+		if !ok {
+			return nil, "", nil
+		}
+		value, err := libraryHash(panel)
+		return &panel, value, err
+	}
+	rows := [][]string{}
+	for _, uid := range uids {
+		left, leftHash, err1 := hash(library1, uid)
+		right, rightHash, err2 := hash(library2, uid)
+		if err := errors.Join(err1, err2); err != nil {
+			return rows, err
+		}
+		record, found := state.Get(server1.Name, server2.Name, libraryKey(uid))
+		status := classify(leftHash, rightHash, record, found)
+		var name string
+		if left != nil {
+			name = left.Name
+		} else {
+			name = right.Name
+		}
+		rows = append(rows, []string{name, uid, string(status)})
+		if opts.dryRun {
+			continue
+		}
+		var err error
+		switch status {
+		case stateUnchanged:
+			record.Left = baseline.Entry{Hash: leftHash, Version: left.Version}
+			record.Right = baseline.Entry{Hash: rightHash, Version: right.Version}
+		case stateChangedLeft:
+			record.Left = baseline.Entry{Hash: leftHash, Version: left.Version}
+			record.Right, err = copyLibraryPanel(opts, server2, server1, right, *left)
+		case stateChangedRight:
+			record.Right = baseline.Entry{Hash: rightHash, Version: right.Version}
+			record.Left, err = copyLibraryPanel(opts, server1, server2, left, *right)
+		default:
+			continue
+		}
+		if err != nil {
+			return rows, err
+		}
+		state.Set(server1.Name, server2.Name, libraryKey(uid), record)
+	}
+	return rows, nil
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/lint"
+)
+
+// readDashboardFile reads dashboard from API response (with meta) or from plain dashboard model.
+func readDashboardFile(path string) (api.DashboardJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return api.DashboardJSON{}, err
+	}
+	dashboard := api.DashboardJSON{}
+	if err = json.Unmarshal(data, &dashboard); err != nil {
+		return dashboard, fmt.Errorf("error in parsing %s: %w", path, err)
+	}
+	if dashboard.Dashboard.UID == "" && dashboard.Dashboard.Title == "" {
+		err = json.Unmarshal(data, &dashboard.Dashboard)
+	}
+	return dashboard, err
+}
+
+// readDashboardDir reads dashboards from JSON files under dir. Backup manifests are skipped.
+func readDashboardDir(dir string) ([]api.DashboardJSON, error) {
+	dashboards := []api.DashboardJSON{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".json" || entry.Name() == "manifest.json" {
+			return err
+		}
+		dashboard, err := readDashboardFile(path)
+		if err != nil {
+			return err
+		}
+		if dashboard.Dashboard.Title == "" {
+			dashboard.Dashboard.Title = path
+		}
+		dashboards = append(dashboards, dashboard)
+		return nil
+	})
+	return dashboards, err
+}
+
+// lintDashboards runs rules over dashboards. Findings are ordered by dashboard title.
+func lintDashboards(dashboards []api.DashboardJSON, opts lint.Options) (*listing, bool) {
+	sort.SliceStable(dashboards, func(i, j int) bool {
+		return dashboards[i].Dashboard.Title < dashboards[j].Dashboard.Title
+	})
+	result := &listing{header: []string{"Dashboard", "Panel", "Rule", "Severity", "Message"}}
+	errorFound := false
+	for _, dashboard := range dashboards {
+		for _, finding := range lint.Lint(dashboard, opts) {
+			result.add(finding, finding.Dashboard, finding.Panel, finding.Rule, finding.Severity, finding.Message)
+			errorFound = errorFound || finding.Severity == lint.Error
+		}
+	}
+	return result, errorFound
+}
+
+func lintCmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "lint [server|dir]",
+		Short: "check dashboards against lint rules",
+		Long: "Check dashboards of server or JSON files in directory. " +
+			"Severity of rules is set in lint.rules of config file. With --exit-code, errors make command exit with 2",
+		Args: cobra.ExactArgs(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return loadConfig(cmd, true)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			opts := lint.Options{SchemaVersion: cfg.Lint.SchemaVersion, Severities: cfg.Lint.Rules}
+			if err = opts.Validate(); err != nil {
+				return err
+			}
+			dashboards := []api.DashboardJSON{}
+			if info, statErr := os.Stat(args[0]); statErr == nil && info.IsDir() {
+				if dashboards, err = readDashboardDir(args[0]); err != nil {
+					return err
+				}
+			} else {
+				server, err := getServer(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				boards, err := fetchBoards(server, cfg.Ignore)
+				if err != nil {
+					return err
+				}
+				for _, value := range boards {
+					dashboards = append(dashboards, value.json)
+				}
+			}
+			result, errorFound := lintDashboards(dashboards, opts)
+			if len(result.rows) == 0 && (format == "table" || format == "") {
+				slog.Info("no lint findings", "dashboards", len(dashboards))
+				return nil
+			}
+			if err = result.print(format); err != nil {
+				return err
+			}
+			if errorFound {
+				return exitStatus(cmd, ErrFound)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format: table, json or csv")
+	cmd.Flags().Bool("exit-code", false, "exit with 2, when findings with error severity are found")
+	return cmd
+}
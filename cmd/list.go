@@ -1,52 +1,195 @@
 package cmd
 
 import (
-	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
-	"github.com/jylitalo/grafana-dashboard-sync/api"
-	"github.com/jylitalo/grafana-dashboard-sync/config"
 	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
 )
 
-func listCmd() *cobra.Command {
+type listOptions struct {
+	format  string
+	sort    string
+	query   string
+	folders []string
+	tags    []string
+	types   []string
+	full    bool
+}
+
+// listedDashboard is dashboard in JSON output of list. Version and updates
+// come from full dashboard JSON, which is included only with --full.
+type listedDashboard struct {
+	api.Dashboard
+	Version   int                `json:"version,omitempty"`
+	Updated   string             `json:"updated,omitempty"`
+	UpdatedBy string             `json:"updatedBy,omitempty"`
+	JSON      *api.DashboardJSON `json:"json,omitempty"`
+}
+
+// matchQuery tells if title contains query, ignoring case.
+func matchQuery(title, query string) bool {
+	return strings.Contains(strings.ToLower(title), strings.ToLower(query))
+}
+
+func (opts listOptions) matchDashboard(db api.Dashboard) bool {
+	if !matchQuery(db.Title, opts.query) {
+		return false
+	}
+	if len(opts.folders) > 0 && !slices.Contains(opts.folders, db.FolderTitle) && !slices.Contains(opts.folders, db.FolderUID) {
+		return false
+	}
+	for _, tag := range opts.tags {
+		if !slices.Contains(db.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func listDashboards(dashboards []api.Dashboard, opts listOptions) (*listing, error) {
+	result := &listing{header: []string{"Title", "UID", "Folder", "Tags", "Version", "Updated", "UpdatedBy"}}
+	for _, db := range dashboards {
+		if !opts.matchDashboard(db) {
+			continue
+		}
+		item := listedDashboard{Dashboard: db}
+		version := ""
+		if opts.full {
+			dboard, err := db.GetJSON()
+			if err != nil {
+				return nil, err
+			}
+			item.JSON = &dboard
+			item.Version = dboard.Dashboard.Version
+			item.Updated = dboard.Meta.Updated
+			item.UpdatedBy = dboard.Meta.UpdatedBy
+			version = strconv.Itoa(item.Version)
+		}
+		result.add(item, db.Title, db.UID, db.FolderTitle, strings.Join(db.Tags, ","), version, item.Updated, item.UpdatedBy)
+	}
+	return result, result.sort(opts.sort)
+}
+
+func listDataSources(ds []api.DataSource, opts listOptions) (*listing, error) {
+	result := &listing{header: []string{"Name", "UID", "Type", "URL", "Default"}}
+	for _, item := range ds {
+		if !matchQuery(item.Name, opts.query) || (len(opts.types) > 0 && !slices.Contains(opts.types, item.Type)) {
+			continue
+		}
+		result.add(item, item.Name, item.UID, item.Type, item.URL, strconv.FormatBool(item.IsDefault))
+	}
+	return result, result.sort(opts.sort)
+}
+
+func listFolders(folders []api.Folder, opts listOptions) (*listing, error) {
+	result := &listing{header: []string{"Title", "UID", "Parent"}}
+	for _, item := range folders {
+		if matchQuery(item.Title, opts.query) {
+			result.add(item, item.Title, item.UID, item.ParentUID)
+		}
+	}
+	return result, result.sort(opts.sort)
+}
+
+func listDashboardsCmd(opts *listOptions) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "list [server]",
-		Short: "list configuration",
-		Long:  "Fetch configuration from server and show it on screen",
-		Args:  cobra.MinimumNArgs(1),
+		Use:   "dashboards [server]",
+		Short: "list dashboards",
+		Long:  "List dashboards of server. Version and updates are fetched with --full",
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := cmd.Context()
-			cfg, err := config.Get(ctx)
+			server, err := getServer(cmd.Context(), args[0])
 			if err != nil {
 				return err
 			}
-			server, ok := cfg[args[0]]
-			if !ok {
-				return fmt.Errorf("server (%s) not found from config", args[0])
+			dashboards, err := api.GetDashboards(server)
+			if err != nil {
+				return err
 			}
-			dashdbs, err := api.GetDashboards(server)
+			result, err := listDashboards(dashboards, *opts)
 			if err != nil {
 				return err
 			}
-			fmt.Println("Dashboards:")
-			for _, item := range dashdbs {
-				fmt.Printf("DashDB (%s): %v\n", item.Title, item)
-				dboard, err := item.GetJSON()
-				if err != nil {
-					return err
-				}
-				fmt.Printf("Dashboard (%s): %#v\n", item.Title, dboard)
+			return result.print(opts.format)
+		},
+	}
+	cmd.Flags().StringSliceVar(&opts.folders, "folder", nil, "list only dashboards in these folders (title or uid)")
+	cmd.Flags().StringSliceVar(&opts.tags, "tag", nil, "list only dashboards with all these tags")
+	cmd.Flags().BoolVar(&opts.full, "full", false, "fetch full dashboard JSON")
+	return cmd
+}
+
+func listDataSourcesCmd(opts *listOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "datasources [server]",
+		Short: "list data sources",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server, err := getServer(cmd.Context(), args[0])
+			if err != nil {
+				return err
 			}
 			ds, err := api.GetDataSources(server)
 			if err != nil {
 				return err
 			}
-			fmt.Println("Data sources:")
-			for _, item := range ds {
-				fmt.Printf("%v\n", item)
+			if opts.sort == "title" {
+				opts.sort = "name"
+			}
+			result, err := listDataSources(ds, *opts)
+			if err != nil {
+				return err
 			}
-			return nil
+			return result.print(opts.format)
 		},
 	}
+	cmd.Flags().StringSliceVar(&opts.types, "type", nil, "list only data sources of these types")
+	return cmd
+}
+
+func listFoldersCmd(opts *listOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "folders [server]",
+		Short: "list folders",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server, err := getServer(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			folders, err := api.GetFolders(server)
+			if err != nil {
+				return err
+			}
+			result, err := listFolders(folders, *opts)
+			if err != nil {
+				return err
+			}
+			return result.print(opts.format)
+		},
+	}
+	return cmd
+}
+
+func listCmd() *cobra.Command {
+	opts := &listOptions{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list dashboards, data sources or folders",
+		Long:  "Fetch dashboards, data sources or folders from server and show them as table, JSON or CSV",
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&opts.format, "format", "f", "table", "output format: table, json or csv")
+	flags.StringVar(&opts.sort, "sort", "title", "sort by column")
+	flags.StringVarP(&opts.query, "query", "q", "", "list only items with title or name containing query")
+	cmd.AddCommand(
+		listDashboardsCmd(opts),
+		listDataSourcesCmd(opts),
+		listFoldersCmd(opts),
+	)
 	return cmd
 }
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+func TestListDashboards(t *testing.T) {
+	dashboards := []api.Dashboard{
+		{Title: "Kubernetes", UID: "k8s", FolderTitle: "Infra", Tags: []string{"k8s", "prod"}},
+		{Title: "App Debug", UID: "app", FolderTitle: "Apps", Tags: []string{"k8s"}},
+		{Title: "Scratchpad", UID: "tmp"},
+	}
+	result, err := listDashboards(dashboards, listOptions{sort: "uid", tags: []string{"k8s"}})
+	if err != nil {
+		t.Fatalf("listDashboards failed due to %v", err)
+	}
+	if len(result.rows) != 2 || result.rows[0][1] != "app" || result.rows[1][3] != "k8s,prod" {
+		t.Errorf("wrong rows: %#v", result.rows)
+	}
+	result, _ = listDashboards(dashboards, listOptions{sort: "title", query: "KUBE", folders: []string{"Infra"}})
+	if len(result.rows) != 1 || result.values[0].(listedDashboard).UID != "k8s" {
+		t.Errorf("wrong filtered rows: %#v", result.rows)
+	}
+	if _, err = listDashboards(dashboards, listOptions{sort: "size"}); err == nil {
+		t.Errorf("listDashboards should fail on unknown sort column")
+	}
+}
+
+func TestListingWrite(t *testing.T) {
+	result := &listing{header: []string{"Name", "Version"}}
+	result.add(map[string]int{"version": 10}, "b", "10")
+	result.add(map[string]int{"version": 9}, "a", "9")
+	if err := result.sort("version"); err != nil {
+		t.Fatalf("sort failed due to %v", err)
+	}
+	tests := map[string]string{
+		"csv":  "Name,Version\na,9\nb,10\n",
+		"json": "[\n  {\n    \"version\": 9\n  },\n  {\n    \"version\": 10\n  }\n]\n",
+	}
+	for format, expecting := range tests {
+		buf := &bytes.Buffer{}
+		if err := result.write(buf, format); err != nil {
+			t.Errorf("write(%s) failed due to %v", format, err)
+		}
+		if buf.String() != expecting {
+			t.Errorf("write(%s) returned %q instead of %q", format, buf.String(), expecting)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := result.write(buf, "table"); err != nil || !strings.Contains(buf.String(), "VERSION") {
+		t.Errorf("write(table) returned %q, %v", buf.String(), err)
+	}
+	if err := result.write(buf, "xml"); err == nil {
+		t.Errorf("write should fail on unknown format")
+	}
+}
@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/backup"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/baseline"
+)
+
+// missing marks value, which doesn't exist on that side of merge.
+type missing struct{}
+
+func describe(value interface{}) string {
+	if _, ok := value.(missing); ok {
+		return "(missing)"
+	}
+	data, err := json.Marshal(value)
+	switch {
+	case err != nil:
+		return err.Error()
+	case len(data) == 0 || string(data) == "null":
+		return "(missing)"
+	}
+	return truncLine(string(data))
+}
+
+func field(m map[string]interface{}, key string) interface{} {
+	if value, ok := m[key]; ok {
+		return value
+	}
+	return missing{}
+}
+
+// listKey identifies list item by refId (targets), title (panels), name (variables) or id.
+func listKey(item interface{}) string {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"refId", "title", "name", "id"} {
+		if value, ok := m[key]; ok && value != nil && value != "" {
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+// keyed gives each item unique key. Duplicate keys get running number as suffix.
+// It returns false, if some item doesn't have key.
+func keyed(items []interface{}) (map[string]interface{}, []string, bool) {
+	m := map[string]interface{}{}
+	order := []string{}
+	for _, item := range items {
+		key := listKey(item)
+		if key == "" {
+			return nil, nil, false
+		}
+		k := key
+		for idx := 2; ; idx++ {
+			if _, ok := m[k]; !ok {
+				break
+			}
+			k = fmt.Sprintf("%s#%d", key, idx)
+		}
+		m[k] = item
+		order = append(order, k)
+	}
+	return m, order, true
+}
+
+// mergeList merges lists, which items have keys. Result follows order of left side
+// with items added on right side at the end.
+func mergeList(path string, base, left, right []interface{}) (interface{}, [][]string, bool) {
+	baseMap, _, ok1 := keyed(base)
+	leftMap, leftOrder, ok2 := keyed(left)
+	rightMap, rightOrder, ok3 := keyed(right)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, nil, false
+	}
+	order := leftOrder
+	for _, k := range rightOrder {
+		if _, ok := leftMap[k]; !ok {
+			order = append(order, k)
+		}
+	}
+	result := []interface{}{}
+	conflicts := [][]string{}
+	for _, k := range order {
+		value, conflict := mergeJSON(path+"["+k+"]", field(baseMap, k), field(leftMap, k), field(rightMap, k))
+		conflicts = append(conflicts, conflict...)
+		if _, ok := value.(missing); !ok {
+			result = append(result, value)
+		}
+	}
+	return result, conflicts, true
+}
+
+// mergeJSON does three-way merge of generic JSON values. Objects are merged key by key and
+// lists item by item, when items have keys. Conflicting values are taken from left side.
+func mergeJSON(path string, base, left, right interface{}) (interface{}, [][]string) {
+	switch {
+	case reflect.DeepEqual(left, right), reflect.DeepEqual(base, right):
+		return left, nil
+	case reflect.DeepEqual(base, left):
+		return right, nil
+	}
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+	baseMap, ok1 := base.(map[string]interface{})
+	leftMap, ok2 := left.(map[string]interface{})
+	rightMap, ok3 := right.(map[string]interface{})
+	if ok1 && ok2 && ok3 {
+		keys := []string{}
+		for _, m := range []map[string]interface{}{baseMap, leftMap, rightMap} {
+			for key := range m {
+				if !slices.Contains(keys, key) {
+					keys = append(keys, key)
+				}
+			}
+		}
+		sort.Strings(keys)
+		result := map[string]interface{}{}
+		conflicts := [][]string{}
+		for _, key := range keys {
+			value, conflict := mergeJSON(prefix+key, field(baseMap, key), field(leftMap, key), field(rightMap, key))
+			conflicts = append(conflicts, conflict...)
+			if _, ok := value.(missing); !ok {
+				result[key] = value
+			}
+		}
+		return result, conflicts
+	}
+	baseList, ok1 := base.([]interface{})
+	leftList, ok2 := left.([]interface{})
+	rightList, ok3 := right.([]interface{})
+	if ok1 && ok2 && ok3 {
+		if result, conflicts, ok := mergeList(path, baseList, leftList, rightList); ok {
+			return result, conflicts
+		}
+	}
+	return left, [][]string{{path + " conflict", describe(left), describe(right)}}
+}
+
+// mergeDashboards applies changes made on left and right side relative to base.
+// Whole dashboard content is merged, including fields which aren't modelled.
+// Conflicting items are taken from left side and listed in returned rows.
+func mergeDashboards(base, left, right api.DashboardJSON) (api.DashboardJSON, [][]string, error) {
+	contents := []map[string]interface{}{}
+	for _, dashboard := range []api.DashboardJSON{base, left, right} {
+		content, err := dashboard.Content()
+		if err != nil {
+			return left, nil, err
+		}
+		delete(content, "id")
+		delete(content, "version")
+		contents = append(contents, content)
+	}
+	merged, conflicts := mergeJSON("", contents[0], contents[1], contents[2])
+	content, _ := merged.(map[string]interface{})
+	result, err := left.WithContent(content)
+	if err != nil {
+		return left, nil, err
+	}
+	result.Dashboard.Id = left.Dashboard.Id
+	result.Dashboard.Version = left.Dashboard.Version
+	return result, conflicts, nil
+}
+
+// getAncestor fetches dashboard content from last sync via version history.
+// Version from server2 is rewritten into space of server1.
+func getAncestor(
+	mappings config.Mappings, server1, server2 string, item *syncItem, record baseline.Record,
+) (api.DashboardJSON, error) {
+	ancestor, err1 := getVersionJSON(item.left.db, record.Left.Version)
+	if err1 == nil {
+		return ancestor, nil
+	}
+	ancestor, err2 := getVersionJSON(item.right.db, record.Right.Version)
+	if err2 == nil {
+		return rewriteDashboard(mappings, server2, server1, ancestor)
+	}
+	return api.DashboardJSON{}, fmt.Errorf("common ancestor not found: %w", errors.Join(err1, err2))
+}
+
+// mergeSides merges dashboards of server1 and server2 in space of server1, i.e. data source
+// mappings and transforms of server2 are undone and ones of server1 applied on right side first.
+func mergeSides(
+	mappings config.Mappings, server1, server2 string, ancestor, left, right api.DashboardJSON,
+) (api.DashboardJSON, [][]string, error) {
+	right, err := rewriteDashboard(mappings, server2, server1, right)
+	if err != nil {
+		return left, nil, err
+	}
+	return mergeDashboards(ancestor, left, right)
+}
+
+// mergeItem tries to merge conflicting edits by using version from last sync as common ancestor.
+// It returns false, if merge was not possible.
+func mergeItem(
+	mappings config.Mappings, server1, server2 string, item *syncItem, record baseline.Record, found bool,
+) (api.DashboardJSON, [][]string, bool) {
+	if !found || item.left == nil || item.right == nil {
+		return api.DashboardJSON{}, nil, false
+	}
+	ancestor, err := getAncestor(mappings, server1, server2, item, record)
+	if err != nil {
+		slog.Warn("unable to merge", "dashboard", item.title(), "err", err)
+		return api.DashboardJSON{}, nil, false
+	}
+	merged, conflicts, err := mergeSides(mappings, server1, server2, ancestor, item.left.json, item.right.json)
+	if err != nil {
+		slog.Warn("unable to merge", "dashboard", item.title(), "err", err)
+		return api.DashboardJSON{}, nil, false
+	}
+	return merged, conflicts, true
+}
+
+// pushMerged writes merged dashboard to both servers and returns new baseline.
+func pushMerged(
+	set *backup.Set, mappings config.Mappings, server1, server2 config.Grafana, item *syncItem, merged api.DashboardJSON,
+) (baseline.Record, error) {
+	message := "merged by grafana-dashboard-sync"
+	merged.Meta = item.left.json.Meta
+	left, err := writeDashboard(set, mappings, server1, server1, item.left, merged, message)
+	if err != nil {
+		return baseline.Record{}, err
+	}
+	merged.Meta = item.right.json.Meta
+	right, err := writeDashboard(set, mappings, server1, server2, item.right, merged, message)
+	if err != nil {
+		return baseline.Record{}, err
+	}
+	slog.Info("dashboard merged", server1.Name, server2.Name, "dashboard", item.title())
+	return baseline.Record{Left: left, Right: right}, nil
+}
+
+func mergeCmd() *cobra.Command {
+	var backupDir string
+	var output string
+	var statePath string
+	var write bool
+	cmd := &cobra.Command{
+		Use:   "merge [server1 server2 dashboard]",
+		Short: "merge dashboard edits from two grafanas",
+		Long: "Merge changes made to dashboard on both servers since last sync. " +
+			"Result can be written to file for review or to both servers",
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			state, err := readState(ctx, statePath)
+			if err != nil {
+				return err
+			}
+			db1, err1 := api.FindDashboard(server1, args[2])
+			db2, err2 := api.FindDashboard(server2, args[2])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			json1, err1 := db1.GetJSON()
+			json2, err2 := db2.GetJSON()
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			item := &syncItem{uid: db1.UID, left: &board{db: db1, json: json1}, right: &board{db: db2, json: json2}}
+			record, found := state.Get(server1.Name, server2.Name, item.uid)
+			if !found {
+				return fmt.Errorf("dashboard (%s) has not been synced before", args[2])
+			}
+			ancestor, err := getAncestor(cfg.Mappings, server1.Name, server2.Name, item, record)
+			if err != nil {
+				return err
+			}
+			merged, conflicts, err := mergeSides(cfg.Mappings, server1.Name, server2.Name, ancestor, json1, json2)
+			if err != nil {
+				return err
+			}
+			if len(conflicts) > 0 {
+				renderTable([]string{"Conflict", server1.Name, server2.Name}, conflicts)
+			}
+			if output != "" {
+				data, err := json.MarshalIndent(merged, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err = os.WriteFile(output, data, 0o644); err != nil {
+					return err
+				}
+			}
+			if !write {
+				return nil
+			}
+			if len(conflicts) > 0 {
+				return fmt.Errorf("%d conflicts found, nothing written", len(conflicts))
+			}
+			set, err := newBackupSet(ctx, backupDir)
+			if err != nil {
+				return err
+			}
+			if record, err = pushMerged(set, cfg.Mappings, server1, server2, item, merged); err != nil {
+				return err
+			}
+			state.Set(server1.Name, server2.Name, item.uid, record)
+			return state.Write()
+		},
+	}
+	addBackupFlag(cmd, &backupDir)
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write merged dashboard into file")
+	cmd.Flags().StringVar(&statePath, "state", "", "state file (default is $HOME/.grafana-dashboard-sync-state.json)")
+	cmd.Flags().BoolVar(&write, "write", false, "write merged dashboard to both servers")
+	return cmd
+}
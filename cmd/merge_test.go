@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+func testDashboard(panels ...api.Panel) api.DashboardJSON {
+	dboard := api.DashboardJSON{}
+	dboard.Dashboard.Title = "Kubernetes"
+	dboard.Dashboard.Panels = panels
+	dboard.Dashboard.Templating.List = []api.Variable{{Name: "cluster", Definition: "label_values(instance)"}}
+	return dboard
+}
+
+func TestMergeDashboards(t *testing.T) {
+	failed := api.Panel{Id: 1, Title: "Failed", Type: "stat", Targets: []api.Target{targetFailed}}
+	running := api.Panel{Id: 2, Title: "Running", Type: "stat", Targets: []api.Target{targetRunning}}
+	base := testDashboard(failed, running)
+
+	changedFailed := failed
+	changedFailed.Targets = []api.Target{targetFailed}
+	changedFailed.Targets[0].Expr = "count(kube_pod_status_ready)"
+	left := testDashboard(changedFailed, running)
+	left.Dashboard.Refresh = "1m"
+
+	changedRunning := running
+	changedRunning.Description = "Running deployments"
+	right := testDashboard(failed, changedRunning)
+	right.Dashboard.Templating.List[0].Regex = "/prod.*/"
+
+	merged, conflicts, err := mergeDashboards(base, left, right)
+	if err != nil {
+		t.Errorf("mergeDashboards failed due to %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("non-overlapping changes returned conflicts %#v", conflicts)
+	}
+	panels := merged.Dashboard.Panels
+	if len(panels) != 2 {
+		t.Fatalf("wrong number of panels (%d vs. 2)", len(panels))
+	}
+	if panels[0].Targets[0].Expr != "count(kube_pod_status_ready)" || panels[1].Description != "Running deployments" {
+		t.Errorf("panel changes are missing from %#v", panels)
+	}
+	if merged.Dashboard.Refresh != "1m" || merged.Dashboard.Templating.List[0].Regex != "/prod.*/" {
+		t.Errorf("dashboard changes are missing from %#v", merged.Dashboard)
+	}
+
+	conflicting := failed
+	conflicting.Targets = []api.Target{targetFailed}
+	conflicting.Targets[0].Expr = "count(kube_deployment_created)"
+	right = testDashboard(conflicting, running)
+	_, conflicts, err = mergeDashboards(base, left, right)
+	if err != nil {
+		t.Errorf("mergeDashboards failed due to %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Errorf("wrong number of conflicts (%d vs. 1): %#v", len(conflicts), conflicts)
+	}
+}
+
+func TestMergeUnmodelledFields(t *testing.T) {
+	parse := func(panel string) api.DashboardJSON {
+		dboard := api.DashboardJSON{}
+		data := `{"dashboard": {"uid": "k8s", "title": "Kubernetes", "panels": [` + panel + `]}}`
+		if err := json.Unmarshal([]byte(data), &dboard); err != nil {
+			t.Fatalf("unable to parse dashboard: %v", err)
+		}
+		return dboard
+	}
+	base := parse(`{"id": 1, "title": "CPU", "options": {"legend": {"showLegend": true}, "tooltip": {"mode": "single"}}}`)
+	left := parse(`{"id": 1, "title": "CPU", "options": {"legend": {"showLegend": true}, "tooltip": {"mode": "multi"}}}`)
+	right := parse(`{"id": 1, "title": "CPU", "interval": "1m",
+		"options": {"legend": {"showLegend": false}, "tooltip": {"mode": "single"}}}`)
+	merged, conflicts, err := mergeDashboards(base, left, right)
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("mergeDashboards returned %#v, %v", conflicts, err)
+	}
+	content, err := merged.Content()
+	if err != nil {
+		t.Fatalf("Content failed due to %v", err)
+	}
+	panel := content["panels"].([]interface{})[0].(map[string]interface{})
+	options := panel["options"].(map[string]interface{})
+	if panel["interval"] != "1m" || options["legend"].(map[string]interface{})["showLegend"] != false ||
+		options["tooltip"].(map[string]interface{})["mode"] != "multi" {
+		t.Errorf("changes are missing from merged panel %#v", panel)
+	}
+}
+
+func TestMergeSidesWithMappings(t *testing.T) {
+	mappings := config.Mappings{DataSources: map[string]map[string]string{"prod": {"test-prom": "prod-prom"}}}
+	ds := func(uid string) interface{} { return map[string]interface{}{"type": "prometheus", "uid": uid} }
+	cpu := api.Panel{Id: 1, Title: "CPU", Type: "timeseries", DataSource: ds("test-prom")}
+	mem := api.Panel{Id: 2, Title: "Memory", Type: "timeseries", DataSource: ds("test-prom")}
+	base := testDashboard(cpu, mem)
+
+	changedCPU := cpu
+	changedCPU.Description = "CPU usage"
+	left := testDashboard(changedCPU, mem)
+
+	prodCPU, prodMem := cpu, mem
+	prodCPU.DataSource = ds("prod-prom")
+	prodMem.DataSource = ds("prod-prom")
+	prodMem.Description = "Memory usage"
+	right := testDashboard(prodCPU, prodMem)
+
+	merged, conflicts, err := mergeSides(mappings, "test", "prod", base, left, right)
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("mergeSides returned %#v, %v", conflicts, err)
+	}
+	for _, panel := range merged.Dashboard.Panels {
+		if uid := panel.DataSource.(map[string]interface{})["uid"]; uid != "test-prom" {
+			t.Errorf("panel %s has data source %v of prod", panel.Title, uid)
+		}
+	}
+	if merged.Dashboard.Panels[0].Description != "CPU usage" || merged.Dashboard.Panels[1].Description != "Memory usage" {
+		t.Errorf("changes are missing from %#v", merged.Dashboard.Panels)
+	}
+}
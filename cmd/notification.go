@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// flattenJSON turns value into dotted paths of its scalar fields.
+func flattenJSON(prefix string, value interface{}, fields map[string]string) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		fields[prefix] = err.Error()
+		return
+	}
+	var generic interface{}
+	_ = json.Unmarshal(data, &generic)
+	flattenGeneric(prefix, generic, fields)
+}
+
+func flattenGeneric(prefix string, value interface{}, fields map[string]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flattenGeneric(join(key), item, fields)
+		}
+	case []interface{}:
+		for idx, item := range v {
+			flattenGeneric(fmt.Sprintf("%s[%d]", prefix, idx), item, fields)
+		}
+	case string:
+		fields[prefix] = v
+	case nil:
+	default:
+		fields[prefix] = describe(v)
+	}
+}
+
+// diffNamed compares named items field by field. It returns rows for unique
+// items, labeled with kind, followed by field mismatches.
+func diffNamed(kind string, one, two map[string]map[string]string) [][]string {
+	uniqOne := []string{}
+	uniqTwo := []string{}
+	diff := [][]string{}
+	for name, fields := range one {
+		other, ok := two[name]
+		if !ok {
+			uniqOne = append(uniqOne, name)
+			continue
+		}
+		for _, item := range diffFields(fields, other) {
+			diff = append(diff, []string{name + "\n" + item[0], item[1], item[2]})
+		}
+	}
+	for name := range two {
+		if _, ok := one[name]; !ok {
+			uniqTwo = append(uniqTwo, name)
+		}
+	}
+	sort.Strings(uniqOne)
+	sort.Strings(uniqTwo)
+	sort.Slice(diff, func(i, j int) bool { return diff[i][0] < diff[j][0] })
+	if len(uniqOne) > 0 || len(uniqTwo) > 0 {
+		diff = append([][]string{{"Unique " + kind, strings.Join(uniqOne, "\n"), strings.Join(uniqTwo, "\n")}}, diff...)
+	}
+	return diff
+}
+
+// contactPointKeys maps integrations of contact points by name and type.
+// Integrations of same type in one contact point are numbered.
+func contactPointKeys(cps []api.ContactPoint) map[string]api.ContactPoint {
+	m := map[string]api.ContactPoint{}
+	for _, cp := range cps {
+		key := cp.Name + "/" + cp.Type
+		for idx := 2; ; idx++ {
+			if _, ok := m[key]; !ok {
+				break
+			}
+			key = fmt.Sprintf("%s/%s#%d", cp.Name, cp.Type, idx)
+		}
+		m[key] = cp
+	}
+	return m
+}
+
+func contactPointFields(cp api.ContactPoint) map[string]string {
+	fields := map[string]string{"disableResolveMessage": describe(cp.DisableResolveMessage)}
+	flattenJSON("settings", cp.Settings, fields)
+	return fields
+}
+
+// alerting is notification configuration of grafana.
+type alerting struct {
+	contactPoints map[string]api.ContactPoint
+	policies      api.Policy
+	muteTimings   map[string]api.MuteTiming
+	templates     map[string]api.NotificationTemplate
+}
+
+func getAlerting(server config.Grafana) (alerting, error) {
+	cps, err1 := api.GetContactPoints(server)
+	policies, err2 := api.GetPolicies(server)
+	timings, err3 := api.GetMuteTimings(server)
+	templates, err4 := api.GetTemplates(server)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return alerting{}, err
+	}
+	ret := alerting{
+		contactPoints: contactPointKeys(cps),
+		policies:      policies,
+		muteTimings:   map[string]api.MuteTiming{},
+		templates:     map[string]api.NotificationTemplate{},
+	}
+	for _, item := range timings {
+		ret.muteTimings[item.Name] = item
+	}
+	for _, item := range templates {
+		ret.templates[item.Name] = item
+	}
+	return ret, nil
+}
+
+// fields flattens every kind of notification configuration for diffNamed.
+func (a alerting) fields() map[string]map[string]map[string]string {
+	ret := map[string]map[string]map[string]string{
+		"Contact Points":        {},
+		"Mute Timings":          {},
+		"Templates":             {},
+		"Notification Policies": {},
+	}
+	for key, cp := range a.contactPoints {
+		ret["Contact Points"][key] = contactPointFields(cp)
+	}
+	for name, timing := range a.muteTimings {
+		fields := map[string]string{}
+		flattenJSON("time_intervals", timing.TimeIntervals, fields)
+		ret["Mute Timings"][name] = fields
+	}
+	for name, tmpl := range a.templates {
+		ret["Templates"][name] = map[string]string{"template": tmpl.Template}
+	}
+	policies := a.policies
+	policies.Provenance = ""
+	fields := map[string]string{}
+	flattenJSON("", policies, fields)
+	ret["Notification Policies"]["policy tree"] = fields
+	return ret
+}
+
+var alertingKinds = []string{"Contact Points", "Mute Timings", "Templates", "Notification Policies"}
+
+func diffAlertingConfig(one, two alerting) [][]string {
+	oneFields := one.fields()
+	twoFields := two.fields()
+	diff := [][]string{}
+	for _, kind := range alertingKinds {
+		diff = append(diff, diffNamed(kind, oneFields[kind], twoFields[kind])...)
+	}
+	return diff
+}
+
+func diffAlerting(server1, server2 config.Grafana) error {
+	one, err1 := getAlerting(server1)
+	two, err2 := getAlerting(server2)
+	if err := errors.Join(err1, err2); err != nil {
+		return err
+	}
+	diff := diffAlertingConfig(one, two)
+	if len(diff) == 0 {
+		slog.Info("notification configuration is identical", server1.Name, server2.Name)
+		return nil
+	}
+	renderTable([]string{"", server1.Name, server2.Name}, diff)
+	return ErrFound
+}
+
+func diffAlertingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alerting [server1 server2]",
+		Short: "diff contact points, notification policies, mute timings and templates",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			return exitStatus(cmd, diffAlerting(server1, server2))
+		},
+	}
+	return cmd
+}
+
+// cpSecrets replaces redacted settings of contact point with secrets of target.
+// Secrets are read like data source secrets, contact point name used as data source name.
+// It returns names of settings, which are missing.
+func cpSecrets(target config.Grafana, cp api.ContactPoint) (api.ContactPoint, []string) {
+	settings := map[string]interface{}{}
+	missing := []string{}
+	for key, value := range cp.Settings {
+		if value == api.Redacted {
+			secret, ok := target.Secret(cp.Name, key)
+			if !ok {
+				missing = append(missing, key)
+			}
+			value = secret
+		}
+		settings[key] = value
+	}
+	sort.Strings(missing)
+	cp.Settings = settings
+	return cp, missing
+}
+
+// pushResult is one row of push-alerting output.
+type pushResult struct {
+	kind    string
+	name    string
+	action  string
+	details string
+}
+
+func pushAction(exists, dryRun bool) string {
+	switch {
+	case dryRun && exists:
+		return "would update"
+	case dryRun:
+		return "would create"
+	case exists:
+		return "updated"
+	}
+	return "created"
+}
+
+// pushAlerting makes notification configuration of target match source. Templates
+// and mute timings are pushed first and policy tree last, because it refers to others.
+func pushAlerting(source, target config.Grafana, dryRun bool) ([]pushResult, error) {
+	one, err1 := getAlerting(source)
+	two, err2 := getAlerting(target)
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, err
+	}
+	oneFields := one.fields()
+	twoFields := two.fields()
+	unchanged := func(kind, name string) bool {
+		other, ok := twoFields[kind][name]
+		return ok && len(diffFields(oneFields[kind][name], other)) == 0
+	}
+	results := []pushResult{}
+	push := func(kind, name string, exists bool, fn func() error) error {
+		result := pushResult{kind: kind, name: name, action: pushAction(exists, dryRun)}
+		if unchanged(kind, name) {
+			result.action = "unchanged"
+		} else if !dryRun {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		results = append(results, result)
+		return nil
+	}
+	for _, name := range sortedKeys(one.templates) {
+		current, exists := two.templates[name]
+		tmpl := one.templates[name]
+		tmpl.Version = current.Version
+		err := push("Templates", name, exists, func() error { return api.PutTemplate(target, tmpl, exists) })
+		if err != nil {
+			return results, err
+		}
+	}
+	for _, name := range sortedKeys(one.muteTimings) {
+		current, exists := two.muteTimings[name]
+		timing := one.muteTimings[name]
+		timing.Version = current.Version
+		err := push("Mute Timings", name, exists, func() error { return api.PutMuteTiming(target, timing, exists) })
+		if err != nil {
+			return results, err
+		}
+	}
+	receivers := map[string]bool{}
+	for _, cp := range two.contactPoints {
+		receivers[cp.Name] = true
+	}
+	for _, key := range sortedKeys(one.contactPoints) {
+		current, exists := two.contactPoints[key]
+		cp, missing := cpSecrets(target, one.contactPoints[key])
+		if len(missing) > 0 && !unchanged("Contact Points", key) {
+			results = append(results, pushResult{
+				kind: "Contact Points", name: key, action: "skipped",
+				details: "missing secrets: " + strings.Join(missing, ", "),
+			})
+			slog.Warn("contact point skipped", "contactpoint", key, "missing", missing)
+			continue
+		}
+		cp.UID = current.UID
+		err := push("Contact Points", key, exists, func() error { return api.PutContactPoint(target, cp) })
+		if err != nil {
+			return results, err
+		}
+		receivers[cp.Name] = true
+	}
+	if missing := missingReceivers(one.policies, receivers); len(missing) > 0 {
+		results = append(results, pushResult{
+			kind: "Notification Policies", name: "policy tree", action: "skipped",
+			details: "missing contact points: " + strings.Join(missing, ", "),
+		})
+		slog.Warn("policy tree skipped", "missing", missing)
+		return results, nil
+	}
+	err := push("Notification Policies", "policy tree", true, func() error { return api.PutPolicies(target, one.policies) })
+	return results, err
+}
+
+// missingReceivers lists receivers of policy tree, which aren't among given contact points.
+func missingReceivers(policy api.Policy, receivers map[string]bool) []string {
+	missing := []string{}
+	if policy.Receiver != "" && !receivers[policy.Receiver] {
+		missing = append(missing, policy.Receiver)
+	}
+	for _, route := range policy.Routes {
+		for _, name := range missingReceivers(route, receivers) {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+		}
+	}
+	return missing
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func pushAlertingCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "push-alerting [source target]",
+		Short: "copy contact points, notification policies, mute timings and templates",
+		Long: "Create or update notification configuration on target to match source. " +
+			"Redacted settings of contact points are read from target's secrets in config " +
+			"(contact point name in place of data source name) or from " +
+			"GDS_SECRET_<SERVER>_<CONTACTPOINT>_<SETTING> environment variables",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			source, err1 := getServer(ctx, args[0])
+			target, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			results, err := pushAlerting(source, target, dryRun)
+			rows := [][]string{}
+			for _, item := range results {
+				rows = append(rows, []string{item.kind, item.name, item.action, item.details})
+			}
+			renderTable([]string{"Kind", "Name", "Action", "Details"}, rows)
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show what would be pushed")
+	return cmd
+}
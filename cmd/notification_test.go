@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+func TestFlattenJSON(t *testing.T) {
+	fields := map[string]string{}
+	flattenJSON("", api.Policy{Receiver: "ops", Routes: []api.Policy{{Receiver: "dev", GroupBy: []string{"team"}}}}, fields)
+	expecting := map[string]string{"receiver": "ops", "routes[0].receiver": "dev", "routes[0].group_by[0]": "team"}
+	if len(fields) != len(expecting) {
+		t.Errorf("flattenJSON returned %#v", fields)
+	}
+	for key, value := range expecting {
+		if fields[key] != value {
+			t.Errorf("flattenJSON returned %q for %s instead of %q", fields[key], key, value)
+		}
+	}
+}
+
+func TestDiffAlertingConfig(t *testing.T) {
+	slack := api.ContactPoint{UID: "a", Name: "ops", Type: "slack", Settings: map[string]interface{}{
+		"recipient": "#ops", "url": api.Redacted,
+	}}
+	one := alerting{
+		contactPoints: contactPointKeys([]api.ContactPoint{slack, slack}),
+		policies:      api.Policy{Receiver: "ops"},
+		templates:     map[string]api.NotificationTemplate{"msg": {Name: "msg", Template: "{{ .Status }}"}},
+	}
+	prodSlack := slack
+	prodSlack.UID = "b"
+	prodSlack.Settings = map[string]interface{}{"recipient": "#ops-prod", "url": api.Redacted}
+	two := alerting{
+		contactPoints: contactPointKeys([]api.ContactPoint{prodSlack}),
+		policies:      api.Policy{Receiver: "ops", Provenance: "api"},
+	}
+	diff := diffAlertingConfig(one, two)
+	expecting := [][]string{
+		{"Unique Contact Points", "ops/slack#2", ""},
+		{"ops/slack\nsettings.recipient mismatch", "#ops", "#ops-prod"},
+		{"Unique Templates", "msg", ""},
+	}
+	if !reflect.DeepEqual(diff, expecting) {
+		t.Errorf("diffAlertingConfig returned %#v", diff)
+	}
+}
+
+func TestCpSecrets(t *testing.T) {
+	target := config.Grafana{Name: "prod", Secrets: map[string]map[string]string{"ops": {"url": "https://hooks"}}}
+	cp := api.ContactPoint{Name: "Ops", Settings: map[string]interface{}{"url": api.Redacted, "token": api.Redacted}}
+	filled, missing := cpSecrets(target, cp)
+	if filled.Settings["url"] != "https://hooks" || len(missing) != 1 || missing[0] != "token" {
+		t.Errorf("cpSecrets returned %#v, %v", filled.Settings, missing)
+	}
+	if cp.Settings["url"] != api.Redacted {
+		t.Errorf("cpSecrets modified original settings")
+	}
+}
+
+func TestMissingReceivers(t *testing.T) {
+	policy := api.Policy{Receiver: "Ops", Routes: []api.Policy{
+		{Receiver: "Pager"}, {Receiver: "Ops", Routes: []api.Policy{{Receiver: "Pager"}, {Receiver: "Mail"}}},
+	}}
+	missing := missingReceivers(policy, map[string]bool{"Ops": true})
+	if strings.Join(missing, ",") != "Pager,Mail" {
+		t.Errorf("missingReceivers returned %v", missing)
+	}
+	if missing = missingReceivers(policy, map[string]bool{"Ops": true, "Pager": true, "Mail": true}); len(missing) != 0 {
+		t.Errorf("missingReceivers returned %v, when all contact points exist", missing)
+	}
+}
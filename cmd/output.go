@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrFound is returned, when diff finds differences or lint finds errors.
+// With --exit-code it makes main exit with 2, so that it can be told apart from other errors (1).
+var ErrFound = errors.New("differences found")
+
+// exitStatus passes errors through, but drops ErrFound unless --exit-code is given.
+func exitStatus(cmd *cobra.Command, err error) error {
+	if !errors.Is(err, ErrFound) {
+		return err
+	}
+	if exitCode, _ := cmd.Flags().GetBool("exit-code"); !exitCode {
+		return nil
+	}
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return ErrFound
+}
+
+// outputFormats are accepted values of --format flag.
+var outputFormats = []string{"table", "json", "csv"}
+
+// listing is result of command as table rows and values for JSON output.
+type listing struct {
+	header []string
+	rows   [][]string
+	values []interface{}
+}
+
+func (l *listing) add(value interface{}, row ...string) {
+	l.rows = append(l.rows, row)
+	l.values = append(l.values, value)
+}
+
+// less compares cells numerically, when both are numbers.
+func less(one, two string) bool {
+	oneNum, err1 := strconv.Atoi(one)
+	twoNum, err2 := strconv.Atoi(two)
+	if err1 == nil && err2 == nil {
+		return oneNum < twoNum
+	}
+	return strings.ToLower(one) < strings.ToLower(two)
+}
+
+// sort orders rows and values by column, which name is case insensitive.
+func (l *listing) sort(column string) error {
+	col := slices.IndexFunc(l.header, func(name string) bool { return strings.EqualFold(name, column) })
+	if col < 0 {
+		return fmt.Errorf("unknown sort column (%s), use one of %s", column, strings.Join(l.header, ", "))
+	}
+	order := make([]int, len(l.rows))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool { return less(l.rows[order[i]][col], l.rows[order[j]][col]) })
+	rows := [][]string{}
+	values := []interface{}{}
+	for _, idx := range order {
+		rows = append(rows, l.rows[idx])
+		values = append(values, l.values[idx])
+	}
+	l.rows, l.values = rows, values
+	return nil
+}
+
+func (l *listing) write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if l.values == nil {
+			return enc.Encode([]interface{}{})
+		}
+		return enc.Encode(l.values)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(l.header); err != nil {
+			return err
+		}
+		return cw.WriteAll(l.rows)
+	case "table", "":
+		renderTableTo(w, l.header, l.rows)
+		return nil
+	}
+	return fmt.Errorf("unknown format (%s), use one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// print writes listing to stdout.
+func (l *listing) print(format string) error {
+	return l.write(os.Stdout, format)
+}
+
+// diffListing turns diff rows into listing. In JSON output each row is object
+// with item and values keyed by server name.
+func diffListing(server1, server2 string, diff [][]string) *listing {
+	result := &listing{header: []string{"", server1, server2}}
+	for _, row := range diff {
+		result.add(map[string]string{"item": row[0], server1: row[1], server2: row[2]}, row...)
+	}
+	return result
+}
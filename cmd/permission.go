@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// permissionSet maps permissions, which aren't inherited, by subject.
+func permissionSet(perms []api.Permission) map[string]string {
+	m := map[string]string{}
+	for _, perm := range perms {
+		if !perm.Inherited {
+			m[perm.Subject()] = perm.Level()
+		}
+	}
+	return m
+}
+
+// diffPermissionSets compares permissions of item. Missing permission is shown as (none).
+func diffPermissionSets(name string, one, two []api.Permission) [][]string {
+	oneSet := permissionSet(one)
+	twoSet := permissionSet(two)
+	for subject := range twoSet {
+		if _, ok := oneSet[subject]; !ok {
+			oneSet[subject] = "(none)"
+		}
+	}
+	for subject := range oneSet {
+		if _, ok := twoSet[subject]; !ok {
+			twoSet[subject] = "(none)"
+		}
+	}
+	diff := [][]string{}
+	for _, item := range diffFields(oneSet, twoSet) {
+		subject := strings.TrimSuffix(item[0], " mismatch")
+		diff = append(diff, []string{name + "\n" + subject, item[1], item[2]})
+	}
+	return diff
+}
+
+// diffPermissions compares permissions of dashboards and folders, which have same title on both servers.
+func diffPermissions(server1, server2 config.Grafana, ignore config.Ignore) error {
+	dashdb1, err1 := api.GetDashboards(server1)
+	dashdb2, err2 := api.GetDashboards(server2)
+	folders1, err3 := api.GetFolders(server1)
+	folders2, err4 := api.GetFolders(server2)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return err
+	}
+	type pair struct {
+		name   string
+		folder bool
+		one    string
+		two    string
+	}
+	pairs := []pair{}
+	byTitle := map[string]string{}
+	for _, db := range filterDashboards(dashdb2, ignore) {
+		byTitle[db.Title] = db.UID
+	}
+	for _, db := range filterDashboards(dashdb1, ignore) {
+		if uid, ok := byTitle[db.Title]; ok {
+			pairs = append(pairs, pair{name: db.Title, one: db.UID, two: uid})
+		}
+	}
+	folderByTitle := map[string]string{}
+	for _, folder := range folders2 {
+		folderByTitle[folder.Title] = folder.UID
+	}
+	for _, folder := range folders1 {
+		if uid, ok := folderByTitle[folder.Title]; ok && !ignore.Dashboard("", "", folder.Title, folder.UID) {
+			pairs = append(pairs, pair{name: "Folder: " + folder.Title, folder: true, one: folder.UID, two: uid})
+		}
+	}
+	diff := [][]string{}
+	for _, item := range pairs {
+		get := api.GetDashboardPermissions
+		if item.folder {
+			get = api.GetFolderPermissions
+		}
+		perms1, err1 := get(server1, item.one)
+		perms2, err2 := get(server2, item.two)
+		if err := errors.Join(err1, err2); err != nil {
+			return err
+		}
+		diff = append(diff, diffPermissionSets(item.name, perms1, perms2)...)
+	}
+	if len(diff) == 0 {
+		slog.Info("permissions are identical", server1.Name, server2.Name)
+		return nil
+	}
+	sort.SliceStable(diff, func(i, j int) bool { return diff[i][0] < diff[j][0] })
+	renderTable([]string{"Permissions", server1.Name, server2.Name}, diff)
+	return ErrFound
+}
+
+// mapPermissions replaces team and user ids with ids of same names on target.
+// It returns subjects, which are missing from target.
+func mapPermissions(perms []api.Permission, teams []api.Team, users []api.OrgUser) ([]api.Permission, []string) {
+	teamIds := map[string]int{}
+	for _, team := range teams {
+		teamIds[team.Name] = team.Id
+	}
+	userIds := map[string]int{}
+	for _, user := range users {
+		userIds[user.Login] = user.UserId
+	}
+	mapped := []api.Permission{}
+	missing := []string{}
+	for _, perm := range perms {
+		if perm.Inherited {
+			continue
+		}
+		var ok bool
+		switch {
+		case perm.TeamId != 0:
+			perm.TeamId, ok = teamIds[perm.Team]
+		case perm.UserId != 0:
+			perm.UserId, ok = userIds[perm.UserLogin]
+		default:
+			ok = true
+		}
+		if !ok {
+			missing = append(missing, perm.Subject())
+			continue
+		}
+		mapped = append(mapped, perm)
+	}
+	return mapped, missing
+}
+
+// pushPermissions copies permissions of dashboard from source to target.
+// Permissions of its folder are left as they are.
+// Teams and users are matched by name and missing ones are left out with warning.
+func pushPermissions(source, target config.Grafana, uid string) error {
+	perms, err1 := api.GetDashboardPermissions(source, uid)
+	current, err2 := api.GetDashboardPermissions(target, uid)
+	teams, err3 := api.GetTeams(target)
+	users, err4 := api.GetOrgUsers(target)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return err
+	}
+	mapped, missing := mapPermissions(perms, teams, users)
+	if len(missing) > 0 {
+		slog.Warn("permissions skipped", "server", target.Name, "dashboard", uid, "missing", missing)
+	}
+	if len(diffPermissionSets(uid, mapped, current)) == 0 {
+		return nil
+	}
+	if err := api.SetDashboardPermissions(target, uid, mapped); err != nil {
+		return fmt.Errorf("error in setting permissions of %s: %w", uid, err)
+	}
+	slog.Info("permissions synced", "from", source.Name, "to", target.Name, "dashboard", uid)
+	return nil
+}
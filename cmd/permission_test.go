@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+func TestDiffPermissionSets(t *testing.T) {
+	one := []api.Permission{
+		{TeamId: 1, Team: "ops", Permission: 2},
+		{Role: "Viewer", Permission: 1},
+		{Role: "Editor", Permission: 2, Inherited: true},
+	}
+	two := []api.Permission{
+		{TeamId: 5, Team: "ops", Permission: 2},
+		{Role: "Viewer", Permission: 1},
+		{Role: "Editor", Permission: 2},
+		{UserId: 3, UserLogin: "alice", Permission: 4},
+	}
+	diff := diffPermissionSets("App", one, two)
+	expecting := [][]string{{"App\nrole:Editor", "(none)", "Edit"}, {"App\nuser:alice", "(none)", "Admin"}}
+	if !reflect.DeepEqual(diff, expecting) {
+		t.Errorf("diffPermissionSets returned %#v", diff)
+	}
+}
+
+func TestMapPermissions(t *testing.T) {
+	perms := []api.Permission{
+		{TeamId: 1, Team: "ops", Permission: 2},
+		{TeamId: 2, Team: "dev", Permission: 1},
+		{UserId: 3, UserLogin: "alice", Permission: 4},
+		{Role: "Viewer", Permission: 1},
+	}
+	mapped, missing := mapPermissions(perms, []api.Team{{Id: 10, Name: "ops"}}, []api.OrgUser{{UserId: 30, Login: "alice"}})
+	if len(mapped) != 3 || mapped[0].TeamId != 10 || mapped[1].UserId != 30 || mapped[2].Role != "Viewer" {
+		t.Errorf("mapPermissions returned %#v", mapped)
+	}
+	if len(missing) != 1 || missing[0] != "team:dev" {
+		t.Errorf("mapPermissions returned missing %v", missing)
+	}
+}
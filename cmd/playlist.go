@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// dashboardMapper finds dashboard of source on target by uid or, failing that, by title.
+type dashboardMapper struct {
+	sourceByID    map[int]api.Dashboard
+	sourceByUID   map[string]api.Dashboard
+	targetByUID   map[string]api.Dashboard
+	targetByTitle map[string]api.Dashboard
+}
+
+func newDashboardMapper(source, target []api.Dashboard) *dashboardMapper {
+	m := &dashboardMapper{
+		sourceByID:    map[int]api.Dashboard{},
+		sourceByUID:   map[string]api.Dashboard{},
+		targetByUID:   map[string]api.Dashboard{},
+		targetByTitle: map[string]api.Dashboard{},
+	}
+	for _, db := range source {
+		m.sourceByID[db.Id] = db
+		m.sourceByUID[db.UID] = db
+	}
+	for _, db := range target {
+		m.targetByUID[db.UID] = db
+		m.targetByTitle[db.Title] = db
+	}
+	return m
+}
+
+// source returns dashboard of source by uid or id.
+func (m *dashboardMapper) source(uid string, id int) (api.Dashboard, bool) {
+	if db, ok := m.sourceByUID[uid]; ok && uid != "" {
+		return db, true
+	}
+	db, ok := m.sourceByID[id]
+	return db, ok && id != 0
+}
+
+// target returns dashboard of target matching to source dashboard with uid or id.
+func (m *dashboardMapper) target(uid string, id int) (api.Dashboard, bool) {
+	db, ok := m.source(uid, id)
+	if !ok {
+		return db, false
+	}
+	if target, ok := m.targetByUID[db.UID]; ok {
+		return target, true
+	}
+	target, ok := m.targetByTitle[db.Title]
+	return target, ok
+}
+
+// dashboardItem returns uid or id of dashboard in playlist item.
+func dashboardItem(item api.PlaylistItem) (string, int, bool) {
+	switch item.Type {
+	case "dashboard_by_uid":
+		return item.Value, 0, true
+	case "dashboard_by_id":
+		id, _ := strconv.Atoi(item.Value)
+		return "", id, true
+	}
+	return "", 0, false
+}
+
+// playlistFields describes playlist items by dashboard title, so that playlists can be
+// compared across servers. Mapper has same server as source and target.
+func playlistFields(playlist api.Playlist, mapper *dashboardMapper) map[string]string {
+	fields := map[string]string{"interval": playlist.Interval}
+	for idx, item := range playlist.Items {
+		value := item.Type + ": " + item.Value
+		if uid, id, ok := dashboardItem(item); ok {
+			value = "dashboard: (missing)"
+			if db, ok := mapper.source(uid, id); ok {
+				value = "dashboard: " + db.Title
+			}
+		} else if item.Type == "dashboard_by_tag" {
+			value = "tag: " + item.Value
+		}
+		fields["items["+strconv.Itoa(idx)+"]"] = value
+	}
+	return fields
+}
+
+// remapPlaylist replaces dashboards of source in playlist with dashboards of target.
+// It returns titles or ids of dashboards that are missing from target.
+func remapPlaylist(playlist api.Playlist, mapper *dashboardMapper) (api.Playlist, []string) {
+	items := []api.PlaylistItem{}
+	missing := []string{}
+	for _, item := range playlist.Items {
+		if uid, id, ok := dashboardItem(item); ok {
+			db, found := mapper.target(uid, id)
+			if !found {
+				if source, ok := mapper.source(uid, id); ok {
+					missing = append(missing, source.Title)
+				} else {
+					missing = append(missing, item.Value)
+				}
+				continue
+			}
+			item = api.PlaylistItem{Type: "dashboard_by_uid", Value: db.UID}
+		}
+		items = append(items, item)
+	}
+	playlist.Items = items
+	return playlist, missing
+}
+
+// fetchPlaylists returns playlists of server, with their items, by name.
+func fetchPlaylists(server config.Grafana) (map[string]api.Playlist, error) {
+	playlists, err := api.GetPlaylists(server)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]api.Playlist{}
+	for _, item := range playlists {
+		full, err := api.GetPlaylist(server, item.UID)
+		if err != nil {
+			return nil, err
+		}
+		m[full.Name] = full
+	}
+	return m, nil
+}
+
+// playlistState has playlists and dashboards of two servers.
+type playlistState struct {
+	one, two  map[string]api.Playlist
+	oneMapper *dashboardMapper
+	twoMapper *dashboardMapper
+	mapper    *dashboardMapper
+}
+
+func getPlaylistState(server1, server2 config.Grafana) (playlistState, error) {
+	playlists1, err1 := fetchPlaylists(server1)
+	playlists2, err2 := fetchPlaylists(server2)
+	dashdb1, err3 := api.GetDashboards(server1)
+	dashdb2, err4 := api.GetDashboards(server2)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return playlistState{}, err
+	}
+	return playlistState{
+		one:       playlists1,
+		two:       playlists2,
+		oneMapper: newDashboardMapper(dashdb1, dashdb1),
+		twoMapper: newDashboardMapper(dashdb2, dashdb2),
+		mapper:    newDashboardMapper(dashdb1, dashdb2),
+	}, nil
+}
+
+func (ps playlistState) diff() [][]string {
+	fields1 := map[string]map[string]string{}
+	fields2 := map[string]map[string]string{}
+	for name, playlist := range ps.one {
+		fields1[name] = playlistFields(playlist, ps.oneMapper)
+	}
+	for name, playlist := range ps.two {
+		fields2[name] = playlistFields(playlist, ps.twoMapper)
+	}
+	return diffNamed("Playlists", fields1, fields2)
+}
+
+func diffPlaylistsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "playlists [server1 server2]",
+		Short: "diff playlists of two grafanas",
+		Long:  "Compare playlists by name. Dashboards in playlists are compared by title",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			state, err := getPlaylistState(server1, server2)
+			if err != nil {
+				return err
+			}
+			diff := state.diff()
+			if len(diff) == 0 {
+				slog.Info("playlists are identical", server1.Name, server2.Name)
+				return nil
+			}
+			renderTable([]string{"", server1.Name, server2.Name}, diff)
+			return exitStatus(cmd, ErrFound)
+		},
+	}
+	return cmd
+}
+
+func pushPlaylistsCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "push-playlists [source target]",
+		Short: "copy playlists from one grafana to another",
+		Long: "Create or update playlists on target to match source by name. " +
+			"Dashboards in playlists are matched by uid or title on target",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			source, err1 := getServer(ctx, args[0])
+			target, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			state, err := getPlaylistState(source, target)
+			if err != nil {
+				return err
+			}
+			names := sortedKeys(state.one)
+			rows := [][]string{}
+			for _, name := range names {
+				playlist, missing := remapPlaylist(state.one[name], state.mapper)
+				current, exists := state.two[name]
+				playlist.UID = current.UID
+				if !exists {
+					playlist.UID = state.one[name].UID
+				}
+				details := ""
+				if len(missing) > 0 {
+					sort.Strings(missing)
+					details = "missing dashboards: " + strings.Join(missing, ", ")
+				}
+				action := pushAction(exists, dryRun)
+				if exists && len(diffFields(
+					playlistFields(playlist, state.twoMapper), playlistFields(current, state.twoMapper),
+				)) == 0 {
+					action = "unchanged"
+				} else if !dryRun {
+					if err = api.PutPlaylist(target, playlist, exists); err != nil {
+						return err
+					}
+				}
+				rows = append(rows, []string{name, action, details})
+			}
+			renderTable([]string{"Playlist", "Action", "Details"}, rows)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show what would be pushed")
+	return cmd
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+func TestRemapPlaylist(t *testing.T) {
+	source := []api.Dashboard{{Id: 1, UID: "a", Title: "Alpha"}, {Id: 2, UID: "b", Title: "Beta"}, {Id: 3, UID: "c", Title: "Gamma"}}
+	target := []api.Dashboard{{Id: 7, UID: "a", Title: "Alpha"}, {Id: 8, UID: "x", Title: "Beta"}}
+	mapper := newDashboardMapper(source, target)
+	playlist := api.Playlist{Name: "tv", Items: []api.PlaylistItem{
+		{Type: "dashboard_by_uid", Value: "a"},
+		{Type: "dashboard_by_id", Value: "2"},
+		{Type: "dashboard_by_uid", Value: "c"},
+		{Type: "dashboard_by_tag", Value: "ops"},
+	}}
+	remapped, missing := remapPlaylist(playlist, mapper)
+	expected := []api.PlaylistItem{
+		{Type: "dashboard_by_uid", Value: "a"},
+		{Type: "dashboard_by_uid", Value: "x"},
+		{Type: "dashboard_by_tag", Value: "ops"},
+	}
+	if !reflect.DeepEqual(remapped.Items, expected) {
+		t.Errorf("remapPlaylist returned %v", remapped.Items)
+	}
+	if !reflect.DeepEqual(missing, []string{"Gamma"}) {
+		t.Errorf("remapPlaylist missing %v", missing)
+	}
+	fields := playlistFields(playlist, newDashboardMapper(source, source))
+	if fields["items[1]"] != "dashboard: Beta" || fields["items[3]"] != "tag: ops" {
+		t.Errorf("playlistFields returned %v", fields)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Time
+		fail     bool
+	}{
+		{value: "", expected: time.Time{}},
+		{value: "7d", expected: now.AddDate(0, 0, -7)},
+		{value: "90m", expected: now.Add(-90 * time.Minute)},
+		{value: "2024-05-01T00:00:00Z", expected: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "1714521600000", expected: time.UnixMilli(1714521600000)},
+		{value: "yesterday", fail: true},
+	}
+	for _, test := range tests {
+		ts, err := parseTime(test.value, now)
+		if (err != nil) != test.fail {
+			t.Errorf("parseTime(%q) returned %v", test.value, err)
+		} else if !ts.Equal(test.expected) {
+			t.Errorf("parseTime(%q) returned %v, expected %v", test.value, ts, test.expected)
+		}
+	}
+}
+
+func TestAnnotationKey(t *testing.T) {
+	mapper := newDashboardMapper([]api.Dashboard{{Id: 1, UID: "a", Title: "Alpha"}, {Id: 2, UID: "b", Title: "Beta"}}, nil)
+	deploy := api.Annotation{Time: 1714521600000, Text: "deploy"}
+	keys := map[string]bool{}
+	for _, annotation := range []api.Annotation{
+		deploy,
+		{Time: deploy.Time, Text: deploy.Text, DashboardUID: "a", PanelId: 1},
+		{Time: deploy.Time, Text: deploy.Text, DashboardUID: "a", PanelId: 2},
+		{Time: deploy.Time, Text: deploy.Text, DashboardId: 2, PanelId: 1},
+		{Time: deploy.Time, Text: deploy.Text, DashboardUID: "gone", PanelId: 1},
+	} {
+		keys[annotationKey(annotation, mapper)] = true
+	}
+	if len(keys) != 5 {
+		t.Errorf("annotations on different dashboards share keys: %v", keys)
+	}
+	beta := api.Annotation{Time: deploy.Time, Text: deploy.Text, DashboardId: 2, PanelId: 1}
+	if key := annotationKey(beta, mapper); key != "2024-05-01T00:00:00Z deploy (Beta, panel 1)" {
+		t.Errorf("annotationKey returned %q", key)
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+func pluginsByID(plugins []api.Plugin) map[string]api.Plugin {
+	m := map[string]api.Plugin{}
+	for _, plugin := range plugins {
+		m[plugin.Id] = plugin
+	}
+	return m
+}
+
+func pluginFields(plugins []api.Plugin) map[string]map[string]string {
+	m := map[string]map[string]string{}
+	for _, plugin := range plugins {
+		m[plugin.Id] = map[string]string{
+			"type":    plugin.Type,
+			"version": plugin.Info.Version,
+			"enabled": describe(plugin.Enabled),
+		}
+	}
+	return m
+}
+
+func diffPluginsCmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "plugins [server1 server2]",
+		Short: "diff installed plugins of two grafanas",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			plugins1, err1 := api.GetPlugins(server1)
+			plugins2, err2 := api.GetPlugins(server2)
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			diff := diffNamed("Plugins", pluginFields(plugins1), pluginFields(plugins2))
+			if len(diff) == 0 && (format == "table" || format == "") {
+				slog.Info("plugins are identical", server1.Name, server2.Name)
+				return nil
+			}
+			if err := diffListing(server1.Name, server2.Name, diff).print(format); err != nil {
+				return err
+			}
+			return exitStatus(cmd, ErrFound)
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format: table, json or csv")
+	return cmd
+}
+
+// missingPlugins returns plugins, which are required but missing or older in installed ones.
+func missingPlugins(required map[string]string, installed map[string]api.Plugin) []string {
+	missing := []string{}
+	for id, version := range required {
+		plugin, ok := installed[id]
+		switch {
+		case !ok:
+			missing = append(missing, id+" (missing)")
+		case version != "" && plugin.Info.Version != "" && api.CompareVersions(plugin.Info.Version, version) < 0:
+			missing = append(missing, fmt.Sprintf("%s (%s < %s)", id, plugin.Info.Version, version))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// pluginCheck warns about dashboards, which need plugins that are missing or older on target.
+// Plugins of each target are fetched once.
+type pluginCheck struct {
+	installed map[string]map[string]api.Plugin
+}
+
+func newPluginCheck() *pluginCheck {
+	return &pluginCheck{installed: map[string]map[string]api.Plugin{}}
+}
+
+func (pc *pluginCheck) check(target config.Grafana, dashboard api.DashboardJSON) {
+	if pc == nil {
+		return
+	}
+	installed, ok := pc.installed[target.Name]
+	if !ok {
+		plugins, err := api.GetPlugins(target)
+		if err != nil {
+			slog.Warn("plugin check skipped", "server", target.Name, "err", err)
+		}
+		installed = pluginsByID(plugins)
+		pc.installed[target.Name] = installed
+		if err != nil {
+			return
+		}
+	}
+	if len(installed) == 0 {
+		return
+	}
+	if missing := missingPlugins(dashboard.Plugins(), installed); len(missing) > 0 {
+		slog.Warn("plugins missing from target", "server", target.Name,
+			"dashboard", dashboard.Dashboard.Title, "plugins", missing)
+	}
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+func TestMissingPlugins(t *testing.T) {
+	clock := api.Plugin{Id: "grafana-clock-panel"}
+	clock.Info.Version = "2.0.0"
+	installed := pluginsByID([]api.Plugin{clock, {Id: "timeseries"}, {Id: "prometheus"}})
+	required := map[string]string{"grafana-clock-panel": "2.1.3", "timeseries": "10.4.0", "loki": "", "prometheus": ""}
+	missing := missingPlugins(required, installed)
+	if strings.Join(missing, ",") != "grafana-clock-panel (2.0.0 < 2.1.3),loki (missing)" {
+		t.Errorf("missingPlugins returned %v", missing)
+	}
+}
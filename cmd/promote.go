@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/backup"
+)
+
+type promoteOptions struct {
+	dryRun      bool
+	to          string
+	backupDir   string
+	backup      *backup.Set
+	ignore      config.Ignore
+	mappings    config.Mappings
+	permissions bool
+	plugins     *pluginCheck
+}
+
+// promoteState tells what promotion does to dashboard on target.
+func promoteState(item *syncItem, mappings config.Mappings, source, target config.Grafana) (string, error) {
+	if item.left == nil {
+		return "only on target", nil
+	}
+	if item.right == nil {
+		return "new", nil
+	}
+	mapped, err := rewriteDashboard(mappings, source.Name, target.Name, item.left.json)
+	if err != nil {
+		return "", err
+	}
+	hash, err := mapped.Hash()
+	if err != nil {
+		return "", err
+	}
+	if hash == item.rightHash {
+		return "unchanged", nil
+	}
+	return "changed", nil
+}
+
+// promoteDashboards copies dashboards one way from source to target.
+// Dashboards that exist only on target are left untouched.
+func promoteDashboards(source, target config.Grafana, opts promoteOptions) error {
+	items, err := getSyncItems(source, target, opts.ignore)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	message := fmt.Sprintf("promoted from %s", source.Name)
+	for _, item := range items {
+		state, err := promoteState(item, opts.mappings, source, target)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, []string{item.title(), item.uid, state})
+		if state != "new" && state != "changed" {
+			continue
+		}
+		opts.plugins.check(target, item.left.json)
+		if opts.dryRun {
+			continue
+		}
+		if err = pushLibraryPanels(opts.backup, source, target, item.left.json, opts.mappings, true); err != nil {
+			return err
+		}
+		if _, err = writeDashboard(opts.backup, opts.mappings, source, target, item.right, item.left.json, message); err != nil {
+			return err
+		}
+		slog.Info("dashboard promoted", "from", source.Name, "to", target.Name, "dashboard", item.title())
+		if opts.permissions {
+			if err = pushPermissions(source, target, item.uid); err != nil {
+				return err
+			}
+		}
+	}
+	renderTable([]string{"Dashboard", "UID", source.Name + " -> " + target.Name}, rows)
+	return nil
+}
+
+func promoteCmd() *cobra.Command {
+	var opts promoteOptions
+	cmd := &cobra.Command{
+		Use:   "promote [from-env]",
+		Short: "promote dashboards to next environment in pipeline",
+		Long: "Copy dashboards from reference server (first server) of environment to all servers " +
+			"of next environment. With --to, promotion continues stage by stage until given environment",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			stages, err := cfg.Stages(args[0], opts.to)
+			if err != nil {
+				return err
+			}
+			if opts.backup, err = newBackupSet(ctx, opts.backupDir); err != nil {
+				return err
+			}
+			opts.ignore = cfg.Ignore
+			opts.mappings = cfg.Mappings
+			opts.plugins = newPluginCheck()
+			for idx := 1; idx < len(stages); idx++ {
+				source, err := getServer(ctx, cfg.Environments[stages[idx-1]][0])
+				if err != nil {
+					return err
+				}
+				slog.Info("promoting", "from", stages[idx-1], "to", stages[idx])
+				for _, name := range cfg.Environments[stages[idx]] {
+					target, err := getServer(ctx, name)
+					if err != nil {
+						return err
+					}
+					if opts.dryRun {
+						if err = diffDashboards(source, target, cfg.Ignore, cfg.Mappings); err != nil {
+							return err
+						}
+					}
+					if err = promoteDashboards(source, target, opts); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "only show differences and what would be promoted")
+	cmd.Flags().StringVar(&opts.to, "to", "", "last environment to promote to (default is next one in pipeline)")
+	cmd.Flags().BoolVar(&opts.permissions, "permissions", false, "copy permissions along with dashboards (folder permissions are not copied)")
+	addBackupFlag(cmd, &opts.backupDir)
+	return cmd
+}
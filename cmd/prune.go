@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/baseline"
+)
+
+// pruneOptions limit which dashboards sync is allowed to delete.
+type pruneOptions struct {
+	enabled      bool
+	yes          bool
+	maxDeletions int
+	folders      []string
+	tags         []string
+}
+
+// deletion is dashboard that still exists on server, but has been deleted from the other one.
+type deletion struct {
+	server config.Grafana
+	item   *syncItem
+	value  *board
+}
+
+// inScope checks dashboard against folder and tag filters.
+// Empty filter matches everything.
+func (opts *pruneOptions) inScope(db api.Dashboard) bool {
+	if len(opts.folders) > 0 && !slices.Contains(opts.folders, db.FolderTitle) &&
+		!slices.Contains(opts.folders, db.FolderUID) {
+		return false
+	}
+	if len(opts.tags) == 0 {
+		return true
+	}
+	for _, tag := range db.Tags {
+		if slices.Contains(opts.tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func confirm(in io.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// pruneDashboards deletes dashboards after checking safety limits and taking backups.
+func pruneDashboards(
+	server1, server2 config.Grafana, state *baseline.State, opts syncOptions, in io.Reader, deletions []deletion,
+) error {
+	if len(deletions) == 0 {
+		return nil
+	}
+	if len(deletions) > opts.prune.maxDeletions {
+		return fmt.Errorf("%d dashboards to delete exceeds maximum of %d, nothing deleted",
+			len(deletions), opts.prune.maxDeletions)
+	}
+	for _, item := range deletions {
+		fmt.Printf("delete %s from %s\n", item.value.db.Title, item.server.Name)
+	}
+	if !opts.prune.yes && !confirm(in, fmt.Sprintf("Delete %d dashboards?", len(deletions))) {
+		slog.Info("deletions cancelled")
+		return nil
+	}
+	for _, item := range deletions {
+		path, err := saveBackup(opts.backup, item.server, item.value)
+		if err != nil {
+			return err
+		}
+		if err = item.value.db.Delete(); err != nil {
+			return err
+		}
+		slog.Info("dashboard deleted", "server", item.server.Name, "dashboard", item.value.db.Title, "backup", path)
+		state.Delete(server1.Name, server2.Name, item.item.uid)
+	}
+	return nil
+}
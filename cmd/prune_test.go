@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+func TestInScope(t *testing.T) {
+	db := api.Dashboard{Title: "Kubernetes", FolderTitle: "Infra", FolderUID: "infra", Tags: []string{"k8s"}}
+	tests := []struct {
+		name      string
+		opts      pruneOptions
+		expecting bool
+	}{
+		{"no filters", pruneOptions{}, true},
+		{"folder title", pruneOptions{folders: []string{"Infra"}}, true},
+		{"folder uid", pruneOptions{folders: []string{"infra"}}, true},
+		{"other folder", pruneOptions{folders: []string{"Apps"}}, false},
+		{"tag", pruneOptions{tags: []string{"prod", "k8s"}}, true},
+		{"other tag", pruneOptions{tags: []string{"prod"}}, false},
+		{"folder and other tag", pruneOptions{folders: []string{"Infra"}, tags: []string{"prod"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := test.opts.inScope(db); value != test.expecting {
+				t.Errorf("inScope returned %v instead of %v", value, test.expecting)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	answers := map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false}
+	for answer, expecting := range answers {
+		if value := confirm(strings.NewReader(answer), "Delete?"); value != expecting {
+			t.Errorf("confirm(%q) returned %v instead of %v", answer, value, expecting)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/config"
 )
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -12,7 +15,91 @@ func Execute(ctx context.Context) error {
 	rootCmd := &cobra.Command{
 		Use:   "grafana-dashboard-sync [dashboard-name]",
 		Short: "Sync dashboard with two grafana instances",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return loadConfig(cmd, false)
+		},
 	}
-	rootCmd.AddCommand(diffCmd(), listCmd())
+	flags := rootCmd.PersistentFlags()
+	flags.String("config", "", "config file (default is $HOME/.grafana-dashboard-sync.yaml, env GDS_CONFIG)")
+	flags.Bool("debug", false, "enable debug logging (env GDS_DEBUG)")
+	flags.Bool("no-color", false, "disable colors in log output (env GDS_NO_COLOR)")
+	flags.String("log-format", "", "log format: text or json (env GDS_LOG_FORMAT)")
+	flags.String("log-file", "", "write logs to file instead of stderr (env GDS_LOG_FILE)")
+	flags.StringArray("server", nil,
+		"ad-hoc server as name=url, bearer is read from GDS_BEARER_<NAME> (env GDS_SERVER)")
+	rootCmd.AddCommand(
+		configCmd(),
+		diffCmd(),
+		historyCmd(),
+		lintCmd(),
+		listCmd(),
+		mergeCmd(),
+		promoteCmd(),
+		pushAlertingCmd(),
+		pushAlertsCmd(),
+		pushAnnotationsCmd(),
+		pushDatasourcesCmd(),
+		pushPlaylistsCmd(),
+		restoreBackupCmd(),
+		restoreCmd(),
+		syncCmd(),
+		usageCmd(),
+	)
 	return rootCmd.ExecuteContext(ctx)
 }
+
+// needsConfig tells if command uses config. Help and shell completion work without config file.
+func needsConfig(cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		switch cmd.Name() {
+		case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return false
+		}
+	}
+	return true
+}
+
+// loadConfig reads config with command line flags applied on top of it and stores it into context.
+func loadConfig(cmd *cobra.Command, skipValidation bool) error {
+	if !needsConfig(cmd) {
+		return nil
+	}
+	flags := cmd.Flags()
+	optFn := func(opts *config.Options) {
+		opts.SkipValidation = skipValidation
+		if file, _ := flags.GetString("config"); file != "" {
+			opts.File = file
+		}
+		opts.LogFormat, _ = flags.GetString("log-format")
+		opts.LogFile, _ = flags.GetString("log-file")
+		opts.Servers, _ = flags.GetStringArray("server")
+		if flags.Changed("debug") {
+			debug, _ := flags.GetBool("debug")
+			opts.Debug = &debug
+		}
+		if flags.Changed("no-color") {
+			noColor, _ := flags.GetBool("no-color")
+			color := !noColor
+			opts.Color = &color
+		}
+	}
+	ctx, err := config.Read(cmd.Context(), optFn)
+	if err != nil {
+		return err
+	}
+	cmd.SetContext(ctx)
+	return nil
+}
+
+// getServer returns named server from config stored in context.
+func getServer(ctx context.Context, name string) (config.Grafana, error) {
+	cfg, err := config.Get(ctx)
+	if err != nil {
+		return config.Grafana{}, err
+	}
+	server, ok := cfg.Servers[name]
+	if !ok {
+		return config.Grafana{}, fmt.Errorf("server (%s) not found from config", name)
+	}
+	return server.Resolve()
+}
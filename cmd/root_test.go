@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestNeedsConfig(t *testing.T) {
+	root := &cobra.Command{Use: "grafana-dashboard-sync"}
+	diff := &cobra.Command{Use: "diff"}
+	help := &cobra.Command{Use: "help"}
+	completion := &cobra.Command{Use: "completion"}
+	bash := &cobra.Command{Use: "bash"}
+	complete := &cobra.Command{Use: cobra.ShellCompRequestCmd}
+	completion.AddCommand(bash)
+	root.AddCommand(diff, help, completion, complete)
+	tests := map[*cobra.Command]bool{diff: true, help: false, bash: false, complete: false}
+	for cmd, expected := range tests {
+		if needsConfig(cmd) != expected {
+			t.Errorf("needsConfig(%s) returned %v instead of %v", cmd.CommandPath(), !expected, expected)
+		}
+	}
+}
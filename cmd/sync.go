@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/backup"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/baseline"
+)
+
+type syncState string
+
+const (
+	stateUnchanged    syncState = "unchanged"
+	stateChangedLeft  syncState = "changed-left"
+	stateChangedRight syncState = "changed-right"
+	stateConflict     syncState = "conflict"
+	stateMerged       syncState = "merged"
+	stateDeletedLeft  syncState = "deleted-left"
+	stateDeletedRight syncState = "deleted-right"
+)
+
+// syncItem is dashboard with same uid on both servers.
+// Missing side has nil board.
+type syncItem struct {
+	uid       string
+	left      *board
+	right     *board
+	leftHash  string
+	rightHash string
+}
+
+func (item *syncItem) title() string {
+	if item.left != nil {
+		return item.left.db.Title
+	}
+	return item.right.db.Title
+}
+
+// classify compares current hashes against baseline from last sync.
+// Empty hash means that dashboard is missing from that server.
+func classify(leftHash, rightHash string, record baseline.Record, found bool) syncState {
+	switch {
+	case leftHash == rightHash:
+		return stateUnchanged
+	case rightHash == "" && !found:
+		return stateChangedLeft
+	case leftHash == "" && !found:
+		return stateChangedRight
+	case rightHash == "" && leftHash == record.Left.Hash:
+		return stateDeletedRight
+	case leftHash == "" && rightHash == record.Right.Hash:
+		return stateDeletedLeft
+	case leftHash == "" || rightHash == "" || !found:
+		return stateConflict
+	}
+	leftChanged := leftHash != record.Left.Hash
+	rightChanged := rightHash != record.Right.Hash
+	switch {
+	case leftChanged && !rightChanged:
+		return stateChangedLeft
+	case !leftChanged && rightChanged:
+		return stateChangedRight
+	case !leftChanged && !rightChanged:
+		// content differs only due to data source mappings or transforms
+		return stateUnchanged
+	}
+	return stateConflict
+}
+
+func dbByUID(dashboards []api.Dashboard) (map[string]board, error) {
+	m := map[string]board{}
+	for _, item := range dashboards {
+		dashboard, err := item.GetJSON()
+		if err != nil {
+			return m, err
+		}
+		m[item.UID] = board{db: item, json: dashboard}
+	}
+	return m, nil
+}
+
+func getSyncItems(server1, server2 config.Grafana, ignore config.Ignore) ([]*syncItem, error) {
+	dashdb1, err1 := api.GetDashboards(server1)
+	dashdb2, err2 := api.GetDashboards(server2)
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, err
+	}
+	dbMap1, err1 := dbByUID(filterDashboards(dashdb1, ignore))
+	dbMap2, err2 := dbByUID(filterDashboards(dashdb2, ignore))
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, err
+	}
+	items := map[string]*syncItem{}
+	for uid, value := range dbMap1 {
+		hash, err := value.json.Hash()
+		if err != nil {
+			return nil, err
+		}
+		items[uid] = &syncItem{uid: uid, left: &value, leftHash: hash}
+	}
+	for uid, value := range dbMap2 {
+		hash, err := value.json.Hash()
+		if err != nil {
+			return nil, err
+		}
+		item, ok := items[uid]
+		if !ok {
+			item = &syncItem{uid: uid}
+			items[uid] = item
+		}
+		item.right = &value
+		item.rightHash = hash
+	}
+	ret := []*syncItem{}
+	for _, item := range items {
+		ret = append(ret, item)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].title() < ret[j].title() })
+	return ret, nil
+}
+
+// rewriteDashboard rewrites dashboard of source server for target server. Data source mappings
+// and replace rules of source are undone first and then mappings and transforms of target are applied.
+func rewriteDashboard(
+	mappings config.Mappings, source, target string, dashboard api.DashboardJSON,
+) (api.DashboardJSON, error) {
+	if source == target {
+		return dashboard, nil
+	}
+	mapped, err := dashboard.MapDataSources(mappings.DataSourcesBetween(source, target))
+	if err != nil {
+		return mapped, err
+	}
+	if mapped, err = mapped.Transform(mappings.InverseTransforms(source)); err != nil {
+		return mapped, err
+	}
+	return mapped.Transform(mappings.Transforms[target])
+}
+
+// writeDashboard rewrites dashboard of source for target and writes it there.
+// It returns state of dashboard on target.
+func writeDashboard(
+	set *backup.Set, mappings config.Mappings, source, target config.Grafana, current *board,
+	dashboard api.DashboardJSON, message string,
+) (baseline.Entry, error) {
+	mapped, err := rewriteDashboard(mappings, source.Name, target.Name, dashboard)
+	if err != nil {
+		return baseline.Entry{}, err
+	}
+	hash, err := mapped.Hash()
+	if err != nil {
+		return baseline.Entry{}, err
+	}
+	version, err := putDashboard(set, target, current, mapped, message)
+	if err != nil {
+		return baseline.Entry{}, err
+	}
+	return baseline.Entry{Hash: hash, Version: version}, nil
+}
+
+// copyDashboard pushes dashboard to target and returns its state there.
+func copyDashboard(opts syncOptions, target, source config.Grafana, current, value *board) (baseline.Entry, error) {
+	message := fmt.Sprintf("synced from %s", source.Name)
+	if err := pushLibraryPanels(opts.backup, source, target, value.json, opts.mappings, false); err != nil {
+		return baseline.Entry{}, err
+	}
+	entry, err := writeDashboard(opts.backup, opts.mappings, source, target, current, value.json, message)
+	if err != nil {
+		return entry, err
+	}
+	slog.Info("dashboard synced", "from", source.Name, "to", target.Name, "dashboard", value.db.Title)
+	if opts.permissions {
+		return entry, pushPermissions(source, target, value.db.UID)
+	}
+	return entry, nil
+}
+
+// diffItem describes conflict between two sides, including dashboards deleted from one side.
+func diffItem(item *syncItem) [][]string {
+	switch {
+	case item.left == nil:
+		return [][]string{{"deleted vs. changed", "(deleted)", "changed since last sync"}}
+	case item.right == nil:
+		return [][]string{{"deleted vs. changed", "changed since last sync", "(deleted)"}}
+	}
+	return diffDashboardJSON(item.left.json, item.right.json)
+}
+
+type syncOptions struct {
+	dryRun      bool
+	backupDir   string
+	backup      *backup.Set
+	ignore      config.Ignore
+	mappings    config.Mappings
+	prune       pruneOptions
+	permissions bool
+	plugins     *pluginCheck
+}
+
+func syncDashboards(server1, server2 config.Grafana, state *baseline.State, opts syncOptions) error {
+	libraryRows, err := syncLibraryPanels(server1, server2, state, opts)
+	if len(libraryRows) > 0 {
+		renderTable([]string{"Library Panel", "UID", "State"}, libraryRows)
+	}
+	if err != nil {
+		return errors.Join(err, state.Write())
+	}
+	items, err := getSyncItems(server1, server2, opts.ignore)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	conflicts := [][]string{}
+	deletions := []deletion{}
+	for _, item := range items {
+		record, found := state.Get(server1.Name, server2.Name, item.uid)
+		status := classify(item.leftHash, item.rightHash, record, found)
+		var merged api.DashboardJSON
+		switch status {
+		case stateConflict:
+			var diff [][]string
+			var ok bool
+			merged, diff, ok = mergeItem(opts.mappings, server1.Name, server2.Name, item, record, found)
+			if !ok {
+				diff = diffItem(item)
+			} else if len(diff) == 0 {
+				status = stateMerged
+			}
+			for _, row := range diff {
+				conflicts = append(conflicts, []string{item.title() + "\n" + row[0], row[1], row[2]})
+			}
+		case stateDeletedRight:
+			if opts.prune.enabled && opts.prune.inScope(item.left.db) {
+				deletions = append(deletions, deletion{server: server1, item: item, value: item.left})
+			}
+		case stateDeletedLeft:
+			if opts.prune.enabled && opts.prune.inScope(item.right.db) {
+				deletions = append(deletions, deletion{server: server2, item: item, value: item.right})
+			}
+		}
+		switch status {
+		case stateChangedLeft:
+			opts.plugins.check(server2, item.left.json)
+		case stateChangedRight:
+			opts.plugins.check(server1, item.right.json)
+		}
+		rows = append(rows, []string{item.title(), item.uid, string(status)})
+		if opts.dryRun {
+			continue
+		}
+		switch status {
+		case stateUnchanged:
+			record.Left = baseline.Entry{Hash: item.leftHash, Version: item.left.json.Dashboard.Version}
+			record.Right = baseline.Entry{Hash: item.rightHash, Version: item.right.json.Dashboard.Version}
+		case stateChangedLeft:
+			record.Left = baseline.Entry{Hash: item.leftHash, Version: item.left.json.Dashboard.Version}
+			record.Right, err = copyDashboard(opts, server2, server1, item.right, item.left)
+		case stateChangedRight:
+			record.Right = baseline.Entry{Hash: item.rightHash, Version: item.right.json.Dashboard.Version}
+			record.Left, err = copyDashboard(opts, server1, server2, item.left, item.right)
+		case stateMerged:
+			record, err = pushMerged(opts.backup, opts.mappings, server1, server2, item, merged)
+		default:
+			continue
+		}
+		if err != nil {
+			return errors.Join(err, state.Write())
+		}
+		state.Set(server1.Name, server2.Name, item.uid, record)
+	}
+	renderTable([]string{"Dashboard", "UID", "State"}, rows)
+	if len(conflicts) > 0 {
+		renderTable([]string{"Conflict", server1.Name, server2.Name}, conflicts)
+	}
+	if opts.dryRun {
+		for _, item := range deletions {
+			slog.Info("would delete", "server", item.server.Name, "dashboard", item.value.db.Title)
+		}
+		return nil
+	}
+	err = pruneDashboards(server1, server2, state, opts, os.Stdin, deletions)
+	return errors.Join(err, state.Write())
+}
+
+// readState reads state file from path, defaults.state from config or default location.
+func readState(ctx context.Context, path string) (*baseline.State, error) {
+	cfg, err := config.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		path = cfg.Defaults.State
+	}
+	if path == "" {
+		defaultPath, err := baseline.DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+	return baseline.Read(path)
+}
+
+func syncCmd() *cobra.Command {
+	var opts syncOptions
+	var statePath string
+	cmd := &cobra.Command{
+		Use:   "sync [server1 server2]",
+		Short: "sync dashboards between two grafanas",
+		Long: "Compare dashboards and library panels against state from last sync, copy the ones " +
+			"that have changed only on one server and report conflicts",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			server1, err1 := getServer(ctx, args[0])
+			server2, err2 := getServer(ctx, args[1])
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			state, err := readState(ctx, statePath)
+			if err != nil {
+				return err
+			}
+			if opts.backup, err = newBackupSet(ctx, opts.backupDir); err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("max-deletions") && cfg.Defaults.MaxDeletions > 0 {
+				opts.prune.maxDeletions = cfg.Defaults.MaxDeletions
+			}
+			opts.ignore = cfg.Ignore
+			opts.mappings = cfg.Mappings
+			opts.plugins = newPluginCheck()
+			return syncDashboards(server1, server2, state, opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "only show what would be synced")
+	cmd.Flags().BoolVar(&opts.prune.enabled, "prune", false, "delete dashboards that have been deleted from the other server")
+	cmd.Flags().BoolVarP(&opts.prune.yes, "yes", "y", false, "delete without confirmation")
+	cmd.Flags().IntVar(&opts.prune.maxDeletions, "max-deletions", 5, "refuse to prune more dashboards than this")
+	cmd.Flags().StringSliceVar(&opts.prune.folders, "folder", nil, "prune only dashboards in these folders (title or uid)")
+	cmd.Flags().StringSliceVar(&opts.prune.tags, "tag", nil, "prune only dashboards with these tags")
+	cmd.Flags().BoolVar(&opts.permissions, "permissions", false, "copy permissions along with dashboards (folder permissions are not copied)")
+	addBackupFlag(cmd, &opts.backupDir)
+	cmd.Flags().StringVar(&statePath, "state", "", "state file (default is $HOME/.grafana-dashboard-sync-state.json)")
+	return cmd
+}
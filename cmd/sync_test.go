@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+	"github.com/jylitalo/grafana-dashboard-sync/pkg/baseline"
+)
+
+func TestClassify(t *testing.T) {
+	record := baseline.Record{Left: baseline.Entry{Hash: "a"}, Right: baseline.Entry{Hash: "a"}}
+	tests := []struct {
+		name      string
+		left      string
+		right     string
+		found     bool
+		expecting syncState
+	}{
+		{"identical", "a", "a", true, stateUnchanged},
+		{"identical without baseline", "b", "b", false, stateUnchanged},
+		{"changed left", "b", "a", true, stateChangedLeft},
+		{"changed right", "a", "b", true, stateChangedRight},
+		{"changed both", "b", "c", true, stateConflict},
+		{"different without baseline", "b", "c", false, stateConflict},
+		{"new on left", "a", "", false, stateChangedLeft},
+		{"new on right", "", "a", false, stateChangedRight},
+		{"deleted from right", "a", "", true, stateDeletedRight},
+		{"deleted from left", "", "a", true, stateDeletedLeft},
+		{"deleted from right, changed on left", "b", "", true, stateConflict},
+		{"deleted from left, changed on right", "", "b", true, stateConflict},
+	}
+	mapped := baseline.Record{Left: baseline.Entry{Hash: "a"}, Right: baseline.Entry{Hash: "b"}}
+	if value := classify("a", "b", mapped, true); value != stateUnchanged {
+		t.Errorf("classify returned %s for mapped dashboard", value)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value := classify(test.left, test.right, record, test.found)
+			if value != test.expecting {
+				t.Errorf("classify returned %s instead of %s", value, test.expecting)
+			}
+		})
+	}
+}
+
+func TestLibraryHash(t *testing.T) {
+	panel := api.LibraryPanel{
+		Id: 1, UID: "cpu", Name: "CPU", Version: 3,
+		Model: map[string]interface{}{"id": 4, "title": "CPU", "gridPos": map[string]interface{}{"x": 0}},
+	}
+	moved := panel
+	moved.Id, moved.Version = 2, 5
+	moved.Model = map[string]interface{}{"id": 7, "title": "CPU", "gridPos": map[string]interface{}{"x": 12}}
+	changed := panel
+	changed.Model = map[string]interface{}{"id": 4, "title": "CPU usage"}
+	renamed := panel
+	renamed.Name = "Processor"
+	hashes := map[string]string{}
+	for name, value := range map[string]api.LibraryPanel{"panel": panel, "moved": moved, "changed": changed, "renamed": renamed} {
+		hash, err := libraryHash(value)
+		if err != nil {
+			t.Fatalf("libraryHash failed due to %v", err)
+		}
+		hashes[name] = hash
+	}
+	if hashes["panel"] != hashes["moved"] {
+		t.Errorf("server specific fields changed hash")
+	}
+	if hashes["panel"] == hashes["changed"] || hashes["panel"] == hashes["renamed"] {
+		t.Errorf("content changes didn't change hash: %v", hashes)
+	}
+}
+
+func TestRewriteDashboard(t *testing.T) {
+	mappings := config.Mappings{
+		DataSources: map[string]map[string]string{"prod": {"test-prom": "prod-prom"}},
+		Transforms: map[string][]config.Rule{
+			"prod": {{Panel: ".*", Path: "targets[*].expr", Replace: `env="test"`, With: `env="prod"`}},
+			"test": {{Path: "templating.list[name=cluster].definition", Set: "label_values(test_instance)"}},
+		},
+	}
+	target := api.Target{RefId: "A", Expr: `up{env="test"}`, DataSource: api.DashDataSource{UID: "test-prom"}}
+	testBoard := testDashboard(api.Panel{Id: 1, Title: "Up", Targets: []api.Target{target}})
+	target.Expr = `up{env="prod"}`
+	target.DataSource.UID = "prod-prom"
+	prodBoard := testDashboard(api.Panel{Id: 1, Title: "Up", Targets: []api.Target{target}})
+	tests := []struct {
+		name           string
+		source, target string
+		dashboard      api.DashboardJSON
+		expr, uid      string
+	}{
+		{"left to right", "test", "prod", testBoard, `up{env="prod"}`, "prod-prom"},
+		{"right to left", "prod", "test", prodBoard, `up{env="test"}`, "test-prom"},
+		{"same server", "prod", "prod", prodBoard, `up{env="prod"}`, "prod-prom"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rewritten, err := rewriteDashboard(mappings, test.source, test.target, test.dashboard)
+			if err != nil {
+				t.Fatalf("rewriteDashboard failed due to %v", err)
+			}
+			value := rewritten.Dashboard.Panels[0].Targets[0]
+			if value.Expr != test.expr || value.DataSource.UID != test.uid {
+				t.Errorf("rewriteDashboard returned %s with %s", value.Expr, value.DataSource.UID)
+			}
+		})
+	}
+	rewritten, _ := rewriteDashboard(mappings, "prod", "test", prodBoard)
+	if definition := rewritten.Dashboard.Templating.List[0].Definition; definition != "label_values(test_instance)" {
+		t.Errorf("transforms of test weren't applied: %s", definition)
+	}
+}
+
+func TestCopyLibraryPanelBack(t *testing.T) {
+	posted := api.LibraryPanel{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+			t.Errorf("unable to decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"result": {}}`))
+	}))
+	defer server.Close()
+	mappings := config.Mappings{DataSources: map[string]map[string]string{
+		"test": {"prometheus": "test-prom"}, "prod": {"prometheus": "prod-prom"},
+	}}
+	test := config.Grafana{Name: "test", URL: server.URL}
+	prod := config.Grafana{Name: "prod"}
+	panel := api.LibraryPanel{UID: "cpu", Name: "CPU", Model: map[string]interface{}{
+		"datasource": map[string]interface{}{"type": "prometheus", "uid": "prod-prom"},
+	}}
+	if _, err := copyLibraryPanel(syncOptions{mappings: mappings}, test, prod, nil, panel); err != nil {
+		t.Fatalf("copyLibraryPanel failed due to %v", err)
+	}
+	if uid := posted.Model["datasource"].(map[string]interface{})["uid"]; uid != "test-prom" {
+		t.Errorf("library panel was written to test with data source %v", uid)
+	}
+}
+
+func TestTargetFolder(t *testing.T) {
+	created := []api.Folder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			folder := api.Folder{}
+			_ = json.NewDecoder(r.Body).Decode(&folder)
+			created = append(created, folder)
+			_ = json.NewEncoder(w).Encode(folder)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id": 1, "uid": "apps-prod", "title": "Apps"}, {"id": 2, "uid": "infra", "title": "Infra"}]`))
+	}))
+	defer server.Close()
+	target := config.Grafana{Name: "prod", URL: server.URL}
+	tests := []struct {
+		uid, title, expecting string
+	}{
+		{"", "", ""},
+		{"infra", "Infrastructure", "infra"},
+		{"apps", "Apps", "apps-prod"},
+		{"ops", "Ops", "ops"},
+	}
+	for _, test := range tests {
+		if uid, err := targetFolder(target, test.uid, test.title); err != nil || uid != test.expecting {
+			t.Errorf("targetFolder(%s, %s) returned %s, %v", test.uid, test.title, uid, err)
+		}
+	}
+	if len(created) != 1 || created[0].Title != "Ops" {
+		t.Errorf("wrong folders created: %#v", created)
+	}
+	if _, err := targetFolder(target, "missing", ""); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("targetFolder without title returned %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+	"github.com/jylitalo/grafana-dashboard-sync/config"
+)
+
+// dsUsage is row of usage report. Status is ok, dangling or unused.
+type dsUsage struct {
+	DataSource string `json:"datasource"`
+	UID        string `json:"uid,omitempty"`
+	Dashboard  string `json:"dashboard,omitempty"`
+	Location   string `json:"location,omitempty"`
+	Variable   string `json:"variable,omitempty"`
+	Status     string `json:"status"`
+}
+
+// dsResolver finds data sources by uid or name. Reference "default" is resolved to default data source.
+type dsResolver struct {
+	byUID  map[string]api.DataSource
+	byName map[string]api.DataSource
+	def    *api.DataSource
+}
+
+func newDsResolver(datasources []api.DataSource) dsResolver {
+	resolver := dsResolver{byUID: map[string]api.DataSource{}, byName: map[string]api.DataSource{}}
+	for idx, ds := range datasources {
+		resolver.byUID[ds.UID] = ds
+		resolver.byName[ds.Name] = ds
+		if ds.IsDefault {
+			resolver.def = &datasources[idx]
+		}
+	}
+	return resolver
+}
+
+func (r dsResolver) resolve(ref string) (api.DataSource, bool) {
+	if ds, ok := r.byUID[ref]; ok {
+		return ds, true
+	}
+	if ds, ok := r.byName[ref]; ok {
+		return ds, true
+	}
+	if ref == "default" && r.def != nil {
+		return *r.def, true
+	}
+	return api.DataSource{}, false
+}
+
+// dataSourceUsage lists references from dashboards to data sources. References, which can't be
+// resolved, are dangling. Without filter, data sources without references are listed as unused.
+// Filter is name or uid of data source, or reference of dangling ones.
+func dataSourceUsage(datasources []api.DataSource, dashboards []api.DashboardJSON, filter string) []dsUsage {
+	resolver := newDsResolver(datasources)
+	used := map[string]bool{}
+	usage := []dsUsage{}
+	for _, dashboard := range dashboards {
+		for _, ref := range dashboard.DataSourceRefs() {
+			item := dsUsage{
+				DataSource: ref.Ref, Dashboard: dashboard.Dashboard.Title,
+				Location: ref.Location, Variable: ref.Variable, Status: "ok",
+			}
+			ds, ok := resolver.resolve(ref.Ref)
+			switch {
+			case ok:
+				item.DataSource, item.UID = ds.Name, ds.UID
+				used[ds.UID] = true
+			case ref.Ref == "":
+				item.DataSource = "$" + ref.Variable
+				item.Status = "dangling"
+			default:
+				item.Status = "dangling"
+			}
+			if filter == "" || filter == item.DataSource || filter == item.UID || filter == ref.Ref {
+				usage = append(usage, item)
+			}
+		}
+	}
+	for _, ds := range datasources {
+		if !used[ds.UID] && (filter == "" || filter == ds.Name || filter == ds.UID) {
+			usage = append(usage, dsUsage{DataSource: ds.Name, UID: ds.UID, Status: "unused"})
+		}
+	}
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].DataSource != usage[j].DataSource {
+			return usage[i].DataSource < usage[j].DataSource
+		}
+		return usage[i].Dashboard < usage[j].Dashboard
+	})
+	return usage
+}
+
+func usageCmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "usage [server] [datasource]",
+		Short: "list references from dashboards to data sources",
+		Long: "List dashboards, panels and variables, which reference data sources, including dangling " +
+			"references to missing data sources. Give data source name or uid to see only its references",
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			cfg, err := config.Get(ctx)
+			if err != nil {
+				return err
+			}
+			server, err := getServer(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			filter := ""
+			if len(args) > 1 {
+				filter = args[1]
+			}
+			datasources, err1 := api.GetDataSources(server)
+			boards, err2 := fetchBoardsBy(server, cfg.Ignore, dbByUID)
+			if err := errors.Join(err1, err2); err != nil {
+				return err
+			}
+			dashboards := []api.DashboardJSON{}
+			for _, value := range boards {
+				dashboards = append(dashboards, value.json)
+			}
+			result := &listing{header: []string{"Data source", "UID", "Dashboard", "Location", "Variable", "Status"}}
+			for _, item := range dataSourceUsage(datasources, dashboards, filter) {
+				result.add(item, item.DataSource, item.UID, item.Dashboard, item.Location, item.Variable, item.Status)
+			}
+			if len(result.rows) == 0 && (format == "table" || format == "") {
+				slog.Info("no references found", "server", server.Name, "datasource", filter)
+				return nil
+			}
+			return result.print(format)
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format: table, json or csv")
+	return cmd
+}
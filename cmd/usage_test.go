@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+func TestDataSourceUsage(t *testing.T) {
+	datasources := []api.DataSource{
+		{UID: "prom-uid", Name: "Prometheus", IsDefault: true},
+		{UID: "loki-uid", Name: "Loki"},
+		{UID: "old-uid", Name: "Graphite"},
+	}
+	dashboard := api.DashboardJSON{}
+	dashboard.Dashboard.Title = "Service"
+	dashboard.Dashboard.Templating.List = []api.Variable{
+		{Name: "ds", Type: "datasource", Query: "prometheus", Current: map[string]interface{}{"value": "prom-uid"}},
+		{Name: "pod", Type: "query", DataSource: map[string]interface{}{"uid": "${ds}"}},
+	}
+	dashboard.Dashboard.Panels = []api.Panel{
+		{Title: "Logs", DataSource: "Loki", Targets: []api.Target{
+			{RefId: "A", DataSource: api.DashDataSource{Type: "loki", UID: "loki-uid"}},
+		}},
+		{Title: "Legacy", Targets: []api.Target{
+			{RefId: "A", DataSource: api.DashDataSource{UID: "${DS_INFLUX}"}},
+			{RefId: "B", DataSource: api.DashDataSource{UID: "deleted-uid"}},
+			{RefId: "C", DataSource: api.DashDataSource{UID: "__expr__"}},
+		}},
+		{Title: "Uptime", Targets: []api.Target{{RefId: "A", Expr: "up"}}},
+		{Title: "Notes", Type: "text"},
+	}
+	tests := []struct {
+		filter    string
+		expecting []string
+	}{
+		{"", []string{
+			"$DS_INFLUX||panel Legacy target A|DS_INFLUX|dangling",
+			"Graphite|old-uid|||unused",
+			"Loki|loki-uid|panel Logs datasource||ok",
+			"Loki|loki-uid|panel Logs target A||ok",
+			"Prometheus|prom-uid|variable pod|ds|ok",
+			"Prometheus|prom-uid|panel Legacy datasource||ok",
+			"Prometheus|prom-uid|panel Uptime datasource||ok",
+			"Prometheus|prom-uid|panel Uptime target A||ok",
+			"deleted-uid||panel Legacy target B||dangling",
+		}},
+		{"loki-uid", []string{"Loki|loki-uid|panel Logs datasource||ok", "Loki|loki-uid|panel Logs target A||ok"}},
+		{"Graphite", []string{"Graphite|old-uid|||unused"}},
+		{"deleted-uid", []string{"deleted-uid||panel Legacy target B||dangling"}},
+	}
+	for _, test := range tests {
+		usage := dataSourceUsage(datasources, []api.DashboardJSON{dashboard}, test.filter)
+		rows := []string{}
+		for _, item := range usage {
+			rows = append(rows, fmt.Sprintf("%s|%s|%s|%s|%s", item.DataSource, item.UID, item.Location, item.Variable, item.Status))
+		}
+		if fmt.Sprint(rows) != fmt.Sprint(test.expecting) {
+			t.Errorf("dataSourceUsage(%q) returned\n%v\ninstead of\n%v", test.filter, rows, test.expecting)
+		}
+	}
+}
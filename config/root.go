@@ -2,33 +2,51 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 
 	"github.com/jylitalo/grafana-dashboard-sync/pkg/logging"
 )
 
-type Grafana struct {
-	Name   string
-	URL    string
-	Bearer string
-}
-
 type Options struct {
+	// Path and Name default to home directory and .grafana-dashboard-sync.
 	Path string
 	Name string
+	// File overrides Path and Name, when given. GDS_CONFIG is used, when none of them is given.
+	File string
+	// Debug, Color, LogFormat, LogFile and Servers override values from config file and environment.
+	// Nil or empty means that value wasn't given.
+	Debug     *bool
+	Color     *bool
+	LogFormat string
+	LogFile   string
+	// Servers are in name=url format. Bearer for new server is read from GDS_BEARER_<NAME>.
+	Servers []string
+	// SkipValidation returns config even if it fails validation.
+	SkipValidation bool
 }
 
-type Config map[string]Grafana
 type ctxType string
 
 const ctxKey ctxType = "grafana-dashboard-sync"
 
+// sections are top level keys of config file. Other top level keys are servers
+// in legacy format, where servers, debug and color were at top level.
+var sections = []string{"servers", "defaults", "mappings", "ignore", "output", "environments", "pipeline", "lint"}
+var legacyOutput = []string{"debug", "color"}
+
 func Get(ctx context.Context) (Config, error) {
 	if ctx == nil {
 		return Config{}, errors.New("context is nil")
@@ -40,52 +58,306 @@ func Get(ctx context.Context) (Config, error) {
 	return value.(Config), nil
 }
 
-func Read(optFns ...func(*Options)) (context.Context, error) {
-	home, err := os.UserHomeDir()
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${ENV_VAR} references with their values.
+// Unlike os.ExpandEnv, it leaves plain $ untouched and fails on unset variables.
+func expandEnv(value string) (string, error) {
+	missing := []string{}
+	expanded := envPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := envPattern.FindStringSubmatch(match)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return env
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable(s) %s not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// expandServer expands environment variables in all string fields of server.
+func expandServer(server Grafana) (Grafana, error) {
+	errs := []error{}
+	for _, field := range []*string{&server.URL, &server.Bearer, &server.BearerFile, &server.BearerCommand} {
+		value, err := expandEnv(*field)
+		*field = value
+		errs = append(errs, err)
+	}
+	for _, fields := range server.Secrets {
+		for key := range fields {
+			value, err := expandEnv(fields[key])
+			fields[key] = value
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return server, fmt.Errorf("servers.%s: %w", server.Name, err)
+	}
+	return server, nil
+}
+
+// decode turns viper settings into Config.
+// Legacy top level servers, debug and color are moved under servers and output.
+func decode(settings map[string]interface{}) (Config, error) {
+	servers := map[string]interface{}{}
+	if value, ok := settings["servers"].(map[string]interface{}); ok {
+		servers = value
+	}
+	output := map[string]interface{}{}
+	if value, ok := settings["output"].(map[string]interface{}); ok {
+		output = value
+	}
+	normalized := map[string]interface{}{"servers": servers, "output": output}
+	for key, value := range settings {
+		switch {
+		case key == "servers" || key == "output":
+		case slices.Contains(sections, key):
+			normalized[key] = value
+		case slices.Contains(legacyOutput, key):
+			output[key] = value
+		default:
+			servers[key] = value
+		}
+	}
+	cfg := Config{Servers: map[string]Grafana{}, Output: Output{Color: true}}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error in UserHomeDir: %w", err)
+		return cfg, err
 	}
-	opts := &Options{
-		Path: home,
-		Name: ".grafana-dashboard-sync",
+	if err = decoder.Decode(normalized); err != nil {
+		return cfg, err
 	}
-	for _, optFn := range optFns {
-		optFn(opts)
+	errs := []error{}
+	for name, server := range cfg.Servers {
+		server.Name = name
+		server, err = expandServer(server)
+		errs = append(errs, err)
+		cfg.Servers[name] = server
 	}
-	vip := viper.GetViper()
-	vip.AddConfigPath(opts.Path)
-	vip.SetConfigName(opts.Name)
-	if err = vip.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("error in ReadInConfig: %w", err)
+	return cfg, errors.Join(errs...)
+}
+
+// dataSourceMappings reads mappings.datasources again from YAML or JSON config file,
+// because viper lowercases map keys and data source uids are case sensitive.
+// Server names stay in lower case like other server keys.
+func dataSourceMappings(path string) (map[string]map[string]string, error) {
+	file := struct {
+		Mappings struct {
+			DataSources map[string]map[string]string `yaml:"datasources" json:"datasources"`
+		} `yaml:"mappings" json:"mappings"`
+	}{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &file)
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	default:
+		return nil, nil
 	}
+	if err != nil || file.Mappings.DataSources == nil {
+		return nil, err
+	}
+	mappings := map[string]map[string]string{}
+	for server, uids := range file.Mappings.DataSources {
+		mappings[strings.ToLower(server)] = uids
+	}
+	return mappings, nil
+}
 
-	booleans := map[string]bool{"color": true, "debug": false}
-	value := Config{}
-	for _, keyName := range vip.AllKeys() {
-		if _, found := booleans[keyName]; found {
-			booleans[keyName] = vip.GetBool(keyName)
+// keyLine returns line of dotted key path in YAML node or 0, if it isn't found.
+func keyLine(node *yaml.Node, path []string) int {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode || len(path) == 0 {
+		return 0
+	}
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if !strings.EqualFold(node.Content[idx].Value, path[0]) {
 			continue
 		}
-		fields := strings.SplitN(keyName, ".", 2)
-		server := fields[0]
-		subKey := strings.ToLower(fields[1])
-		val, ok := value[server]
-		if !ok {
-			val = Grafana{Name: server}
+		if line := keyLine(node.Content[idx+1], path[1:]); line > 0 {
+			return line
 		}
-		s := vip.GetString(keyName)
-		switch {
-		case subKey == "bearer":
-			val.Bearer = s
-		case subKey == "url":
-			val.URL = s
-		default:
-			return nil, fmt.Errorf("unknown key (%s) in config file", keyName)
+		return node.Content[idx].Line
+	}
+	return 0
+}
+
+// withLines adds line numbers from YAML config file into validation errors.
+func withLines(path string, keyErrs []*KeyError) error {
+	root := &yaml.Node{}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yml" || ext == ".yaml" {
+		if data, err := os.ReadFile(path); err == nil {
+			_ = yaml.Unmarshal(data, root)
+		}
+	}
+	errs := []error{}
+	for _, keyErr := range keyErrs {
+		keys := strings.Split(keyErr.Key, ".")
+		if keyErr.Line = keyLine(root, keys); keyErr.Line == 0 && keys[0] == "servers" {
+			keyErr.Line = keyLine(root, keys[1:])
 		}
-		value[fields[0]] = val
+		errs = append(errs, keyErr)
+	}
+	return errors.Join(errs...)
+}
+
+// relativeTo makes relative bearer_file paths relative to directory of config file
+// instead of working directory.
+func (cfg *Config) relativeTo(dir string) {
+	for name, server := range cfg.Servers {
+		if server.BearerFile != "" && !filepath.IsAbs(server.BearerFile) {
+			server.BearerFile = filepath.Join(dir, server.BearerFile)
+			cfg.Servers[name] = server
+		}
+	}
+}
+
+func envName(parts ...string) string {
+	return envReplacer.ReplaceAllString(strings.ToUpper(strings.Join(parts, "_")), "_")
+}
+
+// setServer adds server or overrides url of existing one from name=url string.
+func (cfg *Config) setServer(value string) error {
+	name, url, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("server (%s) should be in format name=url", value)
+	}
+	name = strings.ToLower(name)
+	server, ok := cfg.Servers[name]
+	if !ok {
+		server = Grafana{Name: name}
+	}
+	server.URL = url
+	cfg.Servers[name] = server
+	return nil
+}
+
+// override applies environment variables (GDS_*) and then options on top of config file.
+func (cfg *Config) override(opts *Options) error {
+	errs := []error{}
+	if value, ok := os.LookupEnv("GDS_DEBUG"); ok {
+		debug, err := strconv.ParseBool(value)
+		cfg.Output.Debug = debug
+		errs = append(errs, err)
+	}
+	if value, ok := os.LookupEnv("GDS_NO_COLOR"); ok {
+		noColor, err := strconv.ParseBool(value)
+		cfg.Output.Color = !noColor
+		errs = append(errs, err)
+	}
+	if value, ok := os.LookupEnv("GDS_LOG_FORMAT"); ok {
+		cfg.Output.Format = value
+	}
+	if value, ok := os.LookupEnv("GDS_LOG_FILE"); ok {
+		cfg.Output.File = value
+	}
+	servers := opts.Servers
+	if value, ok := os.LookupEnv("GDS_SERVER"); ok && len(servers) == 0 {
+		servers = strings.Split(value, ",")
+	}
+	for _, value := range servers {
+		errs = append(errs, cfg.setServer(value))
+	}
+	for name, server := range cfg.Servers {
+		if value, ok := os.LookupEnv(envName("GDS_BEARER", name)); ok {
+			server.Bearer = value
+			server.BearerFile = ""
+			server.BearerCommand = ""
+			cfg.Servers[name] = server
+		}
+	}
+	if opts.Debug != nil {
+		cfg.Output.Debug = *opts.Debug
+	}
+	if opts.Color != nil {
+		cfg.Output.Color = *opts.Color
+	}
+	if opts.LogFormat != "" {
+		cfg.Output.Format = opts.LogFormat
+	}
+	if opts.LogFile != "" {
+		cfg.Output.File = opts.LogFile
+	}
+	return errors.Join(errs...)
+}
+
+// Read reads config file and applies environment variables and options on top of it.
+// Precedence is options > environment (GDS_*) > config file.
+// Returned context is derived from ctx and carries the config.
+func Read(ctx context.Context, optFns ...func(*Options)) (context.Context, error) {
+	opts := &Options{}
+	for _, optFn := range optFns {
+		optFn(opts)
+	}
+	if opts.File == "" && opts.Path == "" && opts.Name == "" {
+		opts.File = os.Getenv("GDS_CONFIG")
+	}
+	if opts.Path == "" && opts.File == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("error in UserHomeDir: %w", err)
+		}
+		opts.Path = home
+	}
+	if opts.Name == "" {
+		opts.Name = ".grafana-dashboard-sync"
+	}
+	vip := viper.New()
+	if opts.File != "" {
+		vip.SetConfigFile(opts.File)
+	} else {
+		vip.AddConfigPath(opts.Path)
+		vip.SetConfigName(opts.Name)
+	}
+	err := vip.ReadInConfig()
+	notFound := viper.ConfigFileNotFoundError{}
+	adhoc := len(opts.Servers) > 0 || os.Getenv("GDS_SERVER") != ""
+	if err != nil && !(errors.As(err, &notFound) && adhoc) {
+		return nil, fmt.Errorf("error in ReadInConfig: %w", err)
+	}
+	value, err := decode(vip.AllSettings())
+	if err != nil {
+		return nil, fmt.Errorf("error in %s: %w", vip.ConfigFileUsed(), err)
+	}
+	value.File = vip.ConfigFileUsed()
+	value.relativeTo(filepath.Dir(value.File))
+	if value.File != "" && value.Mappings.DataSources != nil {
+		if value.Mappings.DataSources, err = dataSourceMappings(value.File); err != nil {
+			return nil, fmt.Errorf("error in %s: %w", value.File, err)
+		}
+	}
+	if err = value.override(opts); err != nil {
+		return nil, err
+	}
+	if err = value.Validate(); err != nil && !opts.SkipValidation {
+		return nil, fmt.Errorf("error in %s: %w", value.File, err)
+	}
+	logger, err := logging.SetupSlog(logging.Options{
+		Debug:      value.Output.Debug,
+		Color:      value.Output.Color,
+		Format:     value.Output.Format,
+		File:       value.Output.File,
+		MaxSize:    value.Output.MaxSize,
+		MaxBackups: value.Output.MaxBackups,
+	})
+	if err != nil {
+		return nil, err
 	}
-	slog.SetDefault(logging.SetupSlog(booleans["debug"], booleans["color"]))
+	slog.SetDefault(logger)
 	slog.Debug("config", "value", value)
-	ctx := context.WithValue(context.Background(), ctxKey, value)
-	return ctx, nil
+	return context.WithValue(ctx, ctxKey, value), nil
 }
@@ -1,7 +1,13 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/jylitalo/grafana-dashboard-sync/pkg/logging"
@@ -12,8 +18,9 @@ func Test2GrafanasWithDebug(t *testing.T) {
 		opts.Path = "test-data"
 		opts.Name = "test-1"
 	}
-	slog.SetDefault(logging.SetupSlog(false, false))
-	ctx, err := Read(optFn)
+	logger, _ := logging.SetupSlog(logging.Options{})
+	slog.SetDefault(logger)
+	ctx, err := Read(context.Background(), optFn)
 	if err != nil {
 		t.Errorf("Read failed due to %v", err)
 	}
@@ -21,10 +28,246 @@ func Test2GrafanasWithDebug(t *testing.T) {
 	if err != nil {
 		t.Errorf("Get failed due to %v", err)
 	}
-	if len(data) != 2 {
-		t.Errorf("Wrong number of Grafanas found (%d vs. 2)", len(data))
+	if len(data.Servers) != 2 {
+		t.Errorf("Wrong number of Grafanas found (%d vs. 2)", len(data.Servers))
 	}
 	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
 		t.Errorf("Debug logging has not been enabled")
 	}
 }
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("GDS_CONFIG", "test-data/test-3.yml")
+	ctx, err1 := Read(context.Background(), func(opts *Options) {
+		opts.Path = "test-data"
+		opts.Name = "test-1"
+	})
+	data, err2 := Get(ctx)
+	if err := errors.Join(err1, err2); err != nil || data.Defaults.MaxDeletions != 0 {
+		t.Errorf("GDS_CONFIG overrode path and name given by caller: %v", err)
+	}
+	ctx, err1 = Read(context.Background())
+	data, err2 = Get(ctx)
+	if err := errors.Join(err1, err2); err != nil || data.Defaults.MaxDeletions != 10 {
+		t.Errorf("GDS_CONFIG was not used without options: %v", err)
+	}
+}
+
+func TestReadKeepsContext(t *testing.T) {
+	type parentKey string
+	parent := context.WithValue(context.Background(), parentKey("request"), "abc")
+	ctx, err := Read(parent, func(opts *Options) { opts.File = "test-data/test-3.yml" })
+	if err != nil {
+		t.Fatalf("Read failed due to %v", err)
+	}
+	if ctx.Value(parentKey("request")) != "abc" {
+		t.Errorf("Read didn't derive context from caller's context")
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	optFn := func(opts *Options) {
+		opts.Path = "test-data"
+		opts.Name = "secrets"
+	}
+	ctx, err := Read(context.Background(), optFn)
+	if err != nil {
+		t.Errorf("Read failed due to %v", err)
+	}
+	data, err := Get(ctx)
+	if err != nil {
+		t.Errorf("Get failed due to %v", err)
+	}
+	if value, ok := data.Servers["prod"].Secret("Loki", "basicAuthPassword"); !ok || value != "s3cret" {
+		t.Errorf("Secret from config file returned %s, %v", value, ok)
+	}
+	t.Setenv("GDS_SECRET_TEST_MY_LOKI_BASICAUTHPASSWORD", "from-env")
+	if value, ok := data.Servers["test"].Secret("my-loki", "basicAuthPassword"); !ok || value != "from-env" {
+		t.Errorf("Secret from environment returned %s, %v", value, ok)
+	}
+	if _, ok := data.Servers["test"].Secret("Loki", "basicAuthPassword"); ok {
+		t.Errorf("Secret should be missing from test")
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	optFn := func(opts *Options) {
+		opts.Path = "test-data"
+		opts.Name = "test-2"
+	}
+	t.Setenv("GDS_TEST_HOST", "foo.com")
+	t.Setenv("GDS_TEST_TOKEN", "glsa_from_env")
+	ctx, err := Read(context.Background(), optFn)
+	if err != nil {
+		t.Fatalf("Read failed due to %v", err)
+	}
+	data, err := Get(ctx)
+	if err != nil {
+		t.Errorf("Get failed due to %v", err)
+	}
+	if data.Servers["test"].URL != "https://foo.com" {
+		t.Errorf("URL was not expanded: %s", data.Servers["test"].URL)
+	}
+	if filepath.Base(filepath.Dir(data.Servers["test"].BearerFile)) != "test-data" {
+		t.Errorf("bearer_file is not relative to config file: %s", data.Servers["test"].BearerFile)
+	}
+	if command := data.Redacted().Servers["prod"].BearerCommand; command != "***" {
+		t.Errorf("Redacted leaks bearer_command: %s", command)
+	}
+	expected := map[string]string{"test": "glsa_from_file", "prod": "glsa_from_command", "dev": "glsa_from_env"}
+	for name, bearer := range expected {
+		server, err := data.Servers[name].Resolve()
+		if err != nil {
+			t.Errorf("Resolve of %s failed due to %v", name, err)
+		}
+		if server.Bearer != bearer {
+			t.Errorf("Wrong bearer for %s (%s vs. %s)", name, server.Bearer, bearer)
+		}
+	}
+}
+
+func TestExpandEnvMissing(t *testing.T) {
+	if _, err := expandEnv("${GDS_TEST_SURELY_NOT_SET}"); err == nil {
+		t.Errorf("expandEnv should fail on unset variable")
+	}
+	if value, err := expandEnv("pa$$word"); err != nil || value != "pa$$word" {
+		t.Errorf("expandEnv should leave plain $ untouched, got %s, %v", value, err)
+	}
+}
+
+func TestTypedConfig(t *testing.T) {
+	optFn := func(opts *Options) {
+		opts.Path = "test-data"
+		opts.Name = "test-3"
+	}
+	ctx, err := Read(context.Background(), optFn)
+	if err != nil {
+		t.Fatalf("Read failed due to %v", err)
+	}
+	data, err := Get(ctx)
+	if err != nil {
+		t.Errorf("Get failed due to %v", err)
+	}
+	if len(data.Servers) != 2 || data.Servers["prod"].Name != "prod" {
+		t.Errorf("Wrong servers found: %#v", data.Servers)
+	}
+	if data.Defaults.MaxDeletions != 10 || data.Output.Color {
+		t.Errorf("Wrong defaults or output: %#v, %#v", data.Defaults, data.Output)
+	}
+	if data.Mappings.DataSources["prod"]["test-prometheus"] != "prod-prometheus" ||
+		data.Mappings.Transforms["prod"][0].Regex != `^\[TEST\] ` {
+		t.Errorf("Wrong mappings: %#v", data.Mappings)
+	}
+	if !data.Ignore.Dashboard("Scratchpad", "abc", "", "") || !data.Ignore.Dashboard("App", "abc", "Sandbox", "") {
+		t.Errorf("Dashboards should be ignored: %#v", data.Ignore)
+	}
+	if data.Redacted().Servers["test"].Bearer != "***" || data.Servers["test"].Bearer != "glsa_abc" {
+		t.Errorf("Redacted should mask bearer only from copy")
+	}
+	if data.Redacted().Servers["prod"].BearerFile != "***" {
+		t.Errorf("Redacted should mask bearer_file")
+	}
+	for _, value := range []slog.Value{data.LogValue().Resolve(), data.Servers["test"].LogValue()} {
+		if text := fmt.Sprint(value); strings.Contains(text, "glsa_abc") {
+			t.Errorf("LogValue leaks bearer: %s", text)
+		}
+	}
+	if stages, err := data.Stages("test", ""); err != nil || strings.Join(stages, ",") != "test,prod" {
+		t.Errorf("Wrong stages: %v, %v", stages, err)
+	}
+	if _, err := data.Stages("prod", ""); err == nil {
+		t.Errorf("Stages should fail at end of pipeline")
+	}
+	if names := data.ServerNames(); strings.Join(names, ",") != "test,prod" {
+		t.Errorf("Wrong server names: %v", names)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	optFn := func(opts *Options) {
+		opts.Path = "test-data"
+		opts.Name = "invalid"
+	}
+	_, err := Read(context.Background(), optFn)
+	if err == nil {
+		t.Fatalf("Read should fail on invalid config")
+	}
+	for _, expected := range []string{
+		"line 3: servers.test.url: invalid URL (foo.com)",
+		"line 5: servers.prod: missing token",
+		"line 9: mappings.datasources.staging: unknown server",
+		"line 12: mappings.transforms.prod: rule #1: exactly one of set, replace and regex should be given",
+		"line 15: environments.dev: unknown server (dev)",
+		"line 16: pipeline: unknown environment (staging)",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q missing from error: %v", expected, err)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	debug := false
+	optFn := func(opts *Options) {
+		opts.File = "test-data/test-3.yml"
+		opts.Debug = &debug
+		opts.Servers = []string{"test=https://override.com", "adhoc=http://localhost:3000"}
+	}
+	t.Setenv("GDS_DEBUG", "true")
+	t.Setenv("GDS_LOG_FORMAT", "json")
+	t.Setenv("GDS_BEARER_ADHOC", "glsa_adhoc")
+	ctx, err := Read(context.Background(), optFn)
+	if err != nil {
+		t.Fatalf("Read failed due to %v", err)
+	}
+	data, err := Get(ctx)
+	if err != nil {
+		t.Errorf("Get failed due to %v", err)
+	}
+	if data.Output.Debug || data.Output.Format != "json" {
+		t.Errorf("Wrong output settings: %#v", data.Output)
+	}
+	if data.Servers["test"].URL != "https://override.com" || data.Servers["test"].Bearer != "glsa_abc" {
+		t.Errorf("Wrong test server: %#v", data.Servers["test"])
+	}
+	if data.Servers["adhoc"].Bearer != "glsa_adhoc" {
+		t.Errorf("Wrong adhoc server: %#v", data.Servers["adhoc"])
+	}
+}
+
+func TestMixedCaseMappings(t *testing.T) {
+	optFn := func(opts *Options) {
+		opts.File = "test-data/mappings.yml"
+	}
+	ctx, err := Read(context.Background(), optFn)
+	if err != nil {
+		t.Fatalf("Read failed due to %v", err)
+	}
+	data, _ := Get(ctx)
+	expecting := map[string]map[string]string{
+		"prod": {"P8E80F9AEF21F6940": "PBFA97CFB590B2093", "test-loki": "prod-loki"},
+	}
+	if !reflect.DeepEqual(data.Mappings.DataSources, expecting) {
+		t.Errorf("Wrong data source mappings: %#v", data.Mappings.DataSources)
+	}
+}
+
+func TestDataSourcesBetween(t *testing.T) {
+	mappings := Mappings{DataSources: map[string]map[string]string{
+		"test": {"prometheus": "test-prom"}, "prod": {"prometheus": "prod-prom", "loki": "prod-loki"},
+	}}
+	tests := []struct {
+		source, target string
+		expecting      map[string]string
+	}{
+		{"test", "prod", map[string]string{"test-prom": "prod-prom", "prometheus": "prod-prom", "loki": "prod-loki"}},
+		{"prod", "test", map[string]string{"prod-prom": "test-prom", "prod-loki": "loki", "prometheus": "test-prom"}},
+		{"prod", "dev", map[string]string{"prod-prom": "prometheus", "prod-loki": "loki"}},
+		{"prod", "prod", nil},
+	}
+	for _, test := range tests {
+		if mapping := mappings.DataSourcesBetween(test.source, test.target); !reflect.DeepEqual(mapping, test.expecting) {
+			t.Errorf("DataSourcesBetween(%s, %s) returned %v", test.source, test.target, mapping)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Config is typed content of config file.
+type Config struct {
+	// File is path of config file, if any.
+	File     string             `mapstructure:"-" yaml:"-"`
+	Servers  map[string]Grafana `mapstructure:"servers" yaml:"servers"`
+	Defaults Defaults           `mapstructure:"defaults" yaml:"defaults"`
+	Mappings Mappings           `mapstructure:"mappings" yaml:"mappings"`
+	Ignore   Ignore             `mapstructure:"ignore" yaml:"ignore"`
+	Output   Output             `mapstructure:"output" yaml:"output"`
+	// Environments group servers by name (environment -> servers).
+	// First server of environment is its reference server.
+	Environments map[string][]string `mapstructure:"environments" yaml:"environments,omitempty"`
+	// Pipeline lists environments in promotion order.
+	Pipeline []string `mapstructure:"pipeline" yaml:"pipeline,omitempty"`
+	Lint     Lint     `mapstructure:"lint" yaml:"lint,omitempty"`
+}
+
+type Grafana struct {
+	Name   string `mapstructure:"-" yaml:"-"`
+	URL    string `mapstructure:"url" yaml:"url"`
+	Bearer string `mapstructure:"bearer" yaml:"bearer,omitempty"`
+	// BearerFile and BearerCommand are alternatives for Bearer. They are read by Resolve.
+	BearerFile    string `mapstructure:"bearer_file" yaml:"bearer_file,omitempty"`
+	BearerCommand string `mapstructure:"bearer_command" yaml:"bearer_command,omitempty"`
+	// Secrets has secure fields for data sources and contact points (name -> field -> value).
+	// Keys are in lower case, because viper doesn't preserve case.
+	Secrets map[string]map[string]string `mapstructure:"secrets" yaml:"secrets,omitempty"`
+}
+
+// Defaults are used, when command line flag isn't given.
+type Defaults struct {
+	State        string `mapstructure:"state" yaml:"state,omitempty"`
+	BackupDir    string `mapstructure:"backup_dir" yaml:"backup_dir,omitempty"`
+	MaxDeletions int    `mapstructure:"max_deletions" yaml:"max_deletions,omitempty"`
+}
+
+// Mappings translate identifiers, when dashboards are copied to server.
+type Mappings struct {
+	// DataSources maps data source uids per target server (server -> source uid -> target uid).
+	// Uids keep their case, when config file is YAML or JSON.
+	DataSources map[string]map[string]string `mapstructure:"datasources" yaml:"datasources,omitempty"`
+	// Transforms are rules applied to dashboards written to server (server -> rules).
+	Transforms map[string][]Rule `mapstructure:"transforms" yaml:"transforms,omitempty"`
+}
+
+// Rule changes values at Path of dashboard. Path is relative to dashboard or,
+// when Panel is given, to every panel (nested ones included) with matching title.
+// Path has dot separated keys and list selectors: [*], [index] or [key=value].
+// Exactly one of Set, Replace and Regex should be given. Replace and Regex are replaced With.
+type Rule struct {
+	Dashboard string      `mapstructure:"dashboard" yaml:"dashboard,omitempty"` // regexp for title
+	Panel     string      `mapstructure:"panel" yaml:"panel,omitempty"`         // regexp for panel title
+	Path      string      `mapstructure:"path" yaml:"path"`
+	Set       interface{} `mapstructure:"set" yaml:"set,omitempty"`
+	Replace   string      `mapstructure:"replace" yaml:"replace,omitempty"`
+	Regex     string      `mapstructure:"regex" yaml:"regex,omitempty"`
+	With      string      `mapstructure:"with" yaml:"with,omitempty"`
+}
+
+// Ignore lists items that are left out from diff and sync.
+type Ignore struct {
+	Dashboards  []string `mapstructure:"dashboards" yaml:"dashboards,omitempty"` // title or uid
+	Folders     []string `mapstructure:"folders" yaml:"folders,omitempty"`       // title or uid
+	DataSources []string `mapstructure:"datasources" yaml:"datasources,omitempty"`
+}
+
+// Lint configures lint rules. Rules maps rule name to severity (error, warning or off).
+type Lint struct {
+	SchemaVersion int               `mapstructure:"schema_version" yaml:"schema_version,omitempty"`
+	Rules         map[string]string `mapstructure:"rules" yaml:"rules,omitempty"`
+}
+
+type Output struct {
+	Debug  bool   `mapstructure:"debug" yaml:"debug"`
+	Color  bool   `mapstructure:"color" yaml:"color"`
+	Format string `mapstructure:"format" yaml:"format,omitempty"` // text or json
+	// File is written instead of stderr. It is rotated after MaxSize megabytes.
+	File       string `mapstructure:"file" yaml:"file,omitempty"`
+	MaxSize    int    `mapstructure:"max_size" yaml:"max_size,omitempty"`
+	MaxBackups int    `mapstructure:"max_backups" yaml:"max_backups,omitempty"`
+}
+
+// KeyError is validation error for given config key.
+// Line is filled, when it can be found from config file.
+type KeyError struct {
+	Key  string
+	Line int
+	Msg  string
+}
+
+func (e *KeyError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Key, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Key, e.Msg)
+}
+
+// Dashboard tells if dashboard should be ignored based on its title, uid or folder.
+func (ignore Ignore) Dashboard(title, uid, folderTitle, folderUID string) bool {
+	return slices.Contains(ignore.Dashboards, title) || slices.Contains(ignore.Dashboards, uid) ||
+		(folderTitle != "" && slices.Contains(ignore.Folders, folderTitle)) ||
+		(folderUID != "" && slices.Contains(ignore.Folders, folderUID))
+}
+
+// DataSource tells if data source should be ignored based on its name.
+func (ignore Ignore) DataSource(name string) bool {
+	return slices.Contains(ignore.DataSources, name)
+}
+
+// InverseDataSources returns data source mapping of server reversed, so that it can be
+// undone, when dashboards are copied from server. First uid (in sort order) wins duplicates.
+func (m Mappings) InverseDataSources(server string) map[string]string {
+	mapping := m.DataSources[server]
+	keys := []string{}
+	for from := range mapping {
+		keys = append(keys, from)
+	}
+	sort.Strings(keys)
+	inverse := map[string]string{}
+	for _, from := range keys {
+		if _, ok := inverse[mapping[from]]; !ok {
+			inverse[mapping[from]] = from
+		}
+	}
+	return inverse
+}
+
+// DataSourcesBetween returns data source mapping for copying from source to target server.
+// Mapping of source is undone first and then mapping of target applied.
+func (m Mappings) DataSourcesBetween(source, target string) map[string]string {
+	if source == target {
+		return nil
+	}
+	forward := m.DataSources[target]
+	mapping := map[string]string{}
+	for from, to := range forward {
+		mapping[from] = to
+	}
+	for from, to := range m.InverseDataSources(source) {
+		if mapped, ok := forward[to]; ok {
+			to = mapped
+		}
+		mapping[from] = to
+	}
+	return mapping
+}
+
+// InverseTransforms returns rules, which undo transforms of server in reverse order.
+// Only replace rules with non-empty With can be undone. Set and regex rules need
+// transforms of their own for the other server.
+func (m Mappings) InverseTransforms(server string) []Rule {
+	rules := m.Transforms[server]
+	inverse := []Rule{}
+	for idx := len(rules) - 1; idx >= 0; idx-- {
+		rule := rules[idx]
+		if rule.Replace != "" && rule.With != "" {
+			rule.Replace, rule.With = rule.With, rule.Replace
+			inverse = append(inverse, rule)
+		}
+	}
+	return inverse
+}
+
+func (g Grafana) tokenSources() int {
+	count := 0
+	for _, value := range []string{g.Bearer, g.BearerFile, g.BearerCommand} {
+		if value != "" {
+			count++
+		}
+	}
+	return count
+}
+
+func (rule Rule) validate() error {
+	actions := 0
+	for _, given := range []bool{rule.Set != nil, rule.Replace != "", rule.Regex != ""} {
+		if given {
+			actions++
+		}
+	}
+	switch {
+	case rule.Path == "":
+		return errors.New("missing path")
+	case actions != 1:
+		return errors.New("exactly one of set, replace and regex should be given")
+	}
+	for _, expr := range []string{rule.Dashboard, rule.Panel, rule.Regex} {
+		if _, err := regexp.Compile(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) validate() []*KeyError {
+	errs := []*KeyError{}
+	if len(cfg.Servers) == 0 {
+		errs = append(errs, &KeyError{Key: "servers", Msg: "no servers defined"})
+	}
+	names := []string{}
+	for name := range cfg.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		server := cfg.Servers[name]
+		key := "servers." + name
+		if server.URL == "" {
+			errs = append(errs, &KeyError{Key: key + ".url", Msg: "missing URL"})
+		} else if u, err := url.Parse(server.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, &KeyError{Key: key + ".url", Msg: fmt.Sprintf("invalid URL (%s)", server.URL)})
+		}
+		switch server.tokenSources() {
+		case 0:
+			errs = append(errs, &KeyError{Key: key, Msg: "missing token (bearer, bearer_file or bearer_command)"})
+		case 1:
+		default:
+			errs = append(errs, &KeyError{Key: key, Msg: "only one of bearer, bearer_file and bearer_command can be set"})
+		}
+	}
+	for name := range cfg.Mappings.DataSources {
+		if _, ok := cfg.Servers[name]; !ok {
+			errs = append(errs, &KeyError{Key: "mappings.datasources." + name, Msg: "unknown server"})
+		}
+	}
+	for name, rules := range cfg.Mappings.Transforms {
+		key := "mappings.transforms." + name
+		if _, ok := cfg.Servers[name]; !ok {
+			errs = append(errs, &KeyError{Key: key, Msg: "unknown server"})
+		}
+		for idx, rule := range rules {
+			if err := rule.validate(); err != nil {
+				errs = append(errs, &KeyError{Key: key, Msg: fmt.Sprintf("rule #%d: %v", idx+1, err)})
+			}
+		}
+	}
+	if cfg.Output.Format != "" && cfg.Output.Format != "text" && cfg.Output.Format != "json" {
+		errs = append(errs, &KeyError{Key: "output.format", Msg: fmt.Sprintf("unknown format (%s)", cfg.Output.Format)})
+	}
+	envs := []string{}
+	for name := range cfg.Environments {
+		envs = append(envs, name)
+	}
+	sort.Strings(envs)
+	for _, name := range envs {
+		key := "environments." + name
+		if len(cfg.Environments[name]) == 0 {
+			errs = append(errs, &KeyError{Key: key, Msg: "no servers defined"})
+		}
+		for _, server := range cfg.Environments[name] {
+			if _, ok := cfg.Servers[server]; !ok {
+				errs = append(errs, &KeyError{Key: key, Msg: fmt.Sprintf("unknown server (%s)", server)})
+			}
+		}
+	}
+	for idx, name := range cfg.Pipeline {
+		if _, ok := cfg.Environments[name]; !ok {
+			errs = append(errs, &KeyError{Key: "pipeline", Msg: fmt.Sprintf("unknown environment (%s)", name)})
+		} else if slices.Contains(cfg.Pipeline[:idx], name) {
+			errs = append(errs, &KeyError{Key: "pipeline", Msg: fmt.Sprintf("environment (%s) listed twice", name)})
+		}
+	}
+	if cfg.Output.MaxSize < 0 || cfg.Output.MaxBackups < 0 {
+		errs = append(errs, &KeyError{Key: "output", Msg: "max_size and max_backups can't be negative"})
+	}
+	if cfg.Defaults.MaxDeletions < 0 {
+		errs = append(errs, &KeyError{Key: "defaults.max_deletions", Msg: "can't be negative"})
+	}
+	return errs
+}
+
+// Validate checks that config has everything needed for connecting to servers.
+// Errors have line numbers, when config file is YAML.
+func (cfg *Config) Validate() error {
+	keyErrs := cfg.validate()
+	if len(keyErrs) == 0 {
+		return nil
+	}
+	return withLines(cfg.File, keyErrs)
+}
+
+// Stages returns pipeline starting from environment from and ending to environment to.
+// Empty to means next environment in pipeline.
+func (cfg *Config) Stages(from, to string) ([]string, error) {
+	start := slices.Index(cfg.Pipeline, from)
+	if start < 0 {
+		return nil, fmt.Errorf("environment (%s) not found from pipeline", from)
+	}
+	end := start + 1
+	if to != "" {
+		end = slices.Index(cfg.Pipeline, to)
+		if end < 0 {
+			return nil, fmt.Errorf("environment (%s) not found from pipeline", to)
+		}
+	}
+	if end <= start || end >= len(cfg.Pipeline) {
+		return nil, fmt.Errorf("nothing to promote from %s", from)
+	}
+	return cfg.Pipeline[start : end+1], nil
+}
+
+// ServerNames returns all servers in pipeline order. Servers that aren't
+// in any environment of pipeline come last in alphabetical order.
+func (cfg *Config) ServerNames() []string {
+	names := []string{}
+	for _, env := range cfg.Pipeline {
+		for _, name := range cfg.Environments[env] {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	rest := []string{}
+	for name := range cfg.Servers {
+		if !slices.Contains(names, name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// Redacted returns copy of config with bearer tokens, bearer_file, bearer_command and secrets masked.
+func (cfg Config) Redacted() Config {
+	servers := map[string]Grafana{}
+	for name, server := range cfg.Servers {
+		for _, field := range []*string{&server.Bearer, &server.BearerFile, &server.BearerCommand} {
+			if *field != "" {
+				*field = "***"
+			}
+		}
+		secrets := map[string]map[string]string{}
+		for ds, fields := range server.Secrets {
+			secrets[ds] = map[string]string{}
+			for field := range fields {
+				secrets[ds][field] = "***"
+			}
+		}
+		if len(secrets) > 0 {
+			server.Secrets = secrets
+		}
+		servers[name] = server
+	}
+	cfg.Servers = servers
+	return cfg
+}
+
+// LogValue hides bearer token and secrets of server from logs.
+func (g Grafana) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("name", g.Name), slog.String("url", g.URL)}
+	if g.Bearer != "" {
+		attrs = append(attrs, slog.String("bearer", "***"))
+	}
+	if g.BearerFile != "" {
+		attrs = append(attrs, slog.String("bearer_file", "***"))
+	}
+	if g.BearerCommand != "" {
+		attrs = append(attrs, slog.String("bearer_command", "***"))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue logs redacted copy of config.
+func (cfg Config) LogValue() slog.Value {
+	type plain Config
+	return slog.AnyValue(plain(cfg.Redacted()))
+}
+
+var envReplacer = regexp.MustCompile("[^A-Z0-9]+")
+
+// Secret returns secure field for data source from config file or from
+// environment variable GDS_SECRET_<SERVER>_<DATASOURCE>_<FIELD>.
+func (g Grafana) Secret(datasource, field string) (string, bool) {
+	if value, ok := g.Secrets[strings.ToLower(datasource)][strings.ToLower(field)]; ok {
+		return value, true
+	}
+	return os.LookupEnv(envName("GDS_SECRET", g.Name, datasource, field))
+}
+
+// Resolve reads bearer token from bearer_file or bearer_command, if bearer isn't given directly.
+func (g Grafana) Resolve() (Grafana, error) {
+	switch {
+	case g.tokenSources() > 1:
+		return g, fmt.Errorf("%s: only one of bearer, bearer_file and bearer_command can be set", g.Name)
+	case g.BearerFile != "":
+		data, err := os.ReadFile(g.BearerFile)
+		if err != nil {
+			return g, fmt.Errorf("%s: error in reading bearer_file: %w", g.Name, err)
+		}
+		g.Bearer = strings.TrimSpace(string(data))
+	case g.BearerCommand != "":
+		out, err := exec.Command("sh", "-c", g.BearerCommand).Output()
+		if err != nil {
+			return g, fmt.Errorf("%s: error in running bearer_command: %w", g.Name, err)
+		}
+		g.Bearer = strings.TrimSpace(string(out))
+	}
+	return g, nil
+}
@@ -1,18 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"os"
 
 	"github.com/jylitalo/grafana-dashboard-sync/cmd"
-	"github.com/jylitalo/grafana-dashboard-sync/config"
 )
 
 func main() {
-	ctx, err := config.Read()
-	if err != nil {
-		log.Fatalf("Error: %s", err)
-	}
-	if err := cmd.Execute(ctx); err != nil {
+	err := cmd.Execute(context.Background())
+	switch {
+	case errors.Is(err, cmd.ErrFound):
+		os.Exit(2)
+	case err != nil:
 		log.Fatalf("Error: %s", err)
 	}
 }
@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const manifestName = "manifest.json"
+
+// Kinds of entries, which aren't dashboards.
+const (
+	KindRuleGroup    = "rule-group"
+	KindLibraryPanel = "library-panel"
+)
+
+// Entry describes one saved value in manifest. Kind is empty for dashboards.
+type Entry struct {
+	Server  string `json:"server"`
+	Kind    string `json:"kind,omitempty"`
+	UID     string `json:"uid"`
+	Title   string `json:"title"`
+	Version int    `json:"version"`
+	File    string `json:"file"`
+}
+
+// Manifest lists content of backup set.
+type Manifest struct {
+	Created string  `json:"created"`
+	Entries []Entry `json:"entries"`
+}
+
+// Set is timestamped directory, where dashboards are saved before they get modified.
+// Directory is created on first Save. If name is already taken, running number is added to it.
+type Set struct {
+	Path     string
+	Manifest Manifest
+	created  bool
+}
+
+// DefaultRoot returns location of backup sets in home directory.
+func DefaultRoot() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error in UserHomeDir: %w", err)
+	}
+	return filepath.Join(home, ".grafana-dashboard-sync-backups"), nil
+}
+
+// NewSet returns backup set named after current time under root directory.
+func NewSet(root string) *Set {
+	now := time.Now()
+	return &Set{
+		Path:     filepath.Join(root, now.Format("20060102-150405")),
+		Manifest: Manifest{Created: now.Format(time.RFC3339)},
+	}
+}
+
+// create makes directory of set, unless it has been done already.
+func (set *Set) create() error {
+	if set.created {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(set.Path), 0o700); err != nil {
+		return err
+	}
+	base := set.Path
+	for idx := 1; ; idx++ {
+		err := os.Mkdir(set.Path, 0o700)
+		if !errors.Is(err, fs.ErrExist) {
+			set.created = err == nil
+			return err
+		}
+		set.Path = fmt.Sprintf("%s-%d", base, idx)
+	}
+}
+
+// Open reads manifest of existing backup set.
+func Open(path string) (*Set, error) {
+	data, err := os.ReadFile(filepath.Join(path, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	set := &Set{Path: path, created: true}
+	if err = json.Unmarshal(data, &set.Manifest); err != nil {
+		return nil, fmt.Errorf("error in parsing manifest of %s: %w", path, err)
+	}
+	return set, nil
+}
+
+// Save writes value as JSON into <set>/<server>/<uid>.json and adds it to manifest.
+// Entries, which aren't dashboards, go into <set>/<server>/<kind>/<uid>.json.
+func (set *Set) Save(entry Entry, value interface{}) (string, error) {
+	if err := set.create(); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(entry.Server, entry.Kind)
+	if err := os.MkdirAll(filepath.Join(set.Path, dir), 0o700); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	entry.File = filepath.Join(dir, entry.UID+".json")
+	path := filepath.Join(set.Path, entry.File)
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+	set.Manifest.Entries = append(set.Manifest.Entries, entry)
+	data, err = json.MarshalIndent(set.Manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(filepath.Join(set.Path, manifestName), data, 0o600)
+}
+
+// Load reads saved value of manifest entry.
+func (set *Set) Load(entry Entry, value interface{}) error {
+	data, err := os.ReadFile(filepath.Join(set.Path, entry.File))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
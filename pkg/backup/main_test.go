@@ -0,0 +1,65 @@
+package backup
+
+import (
+	"testing"
+)
+
+func TestSaveAndOpen(t *testing.T) {
+	set := NewSet(t.TempDir())
+	values := map[string]string{"abc": "App Debug", "def": "Kubernetes"}
+	for uid, title := range values {
+		if _, err := set.Save(Entry{Server: "prod", UID: uid, Title: title, Version: 3}, map[string]string{"title": title}); err != nil {
+			t.Errorf("Save failed due to %v", err)
+		}
+	}
+	opened, err := Open(set.Path)
+	if err != nil {
+		t.Fatalf("Open failed due to %v", err)
+	}
+	if len(opened.Manifest.Entries) != len(values) {
+		t.Errorf("Wrong number of entries in manifest (%d vs. %d)", len(opened.Manifest.Entries), len(values))
+	}
+	for _, entry := range opened.Manifest.Entries {
+		value := map[string]string{}
+		if err := opened.Load(entry, &value); err != nil {
+			t.Errorf("Load failed due to %v", err)
+		}
+		if value["title"] != values[entry.UID] || entry.Title != values[entry.UID] {
+			t.Errorf("Wrong content for %s: %#v", entry.UID, value)
+		}
+	}
+}
+
+func TestSetsInSameSecond(t *testing.T) {
+	root := t.TempDir()
+	one, two := NewSet(root), NewSet(root)
+	two.Path = one.Path
+	_, err1 := one.Save(Entry{Server: "prod", UID: "abc"}, map[string]string{"title": "one"})
+	_, err2 := two.Save(Entry{Server: "prod", UID: "abc"}, map[string]string{"title": "two"})
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Save failed due to %v, %v", err1, err2)
+	}
+	if one.Path == two.Path {
+		t.Errorf("sets share directory %s", one.Path)
+	}
+	opened, err := Open(one.Path)
+	if err != nil || len(opened.Manifest.Entries) != 1 {
+		t.Errorf("first set was modified: %#v, %v", opened, err)
+	}
+}
+
+func TestSaveKindsWithSameUID(t *testing.T) {
+	set := NewSet(t.TempDir())
+	kinds := map[string]string{"": "dashboard", KindLibraryPanel: "library panel"}
+	for kind, title := range kinds {
+		if _, err := set.Save(Entry{Server: "prod", Kind: kind, UID: "cpu", Title: title}, map[string]string{"title": title}); err != nil {
+			t.Fatalf("Save failed due to %v", err)
+		}
+	}
+	for _, entry := range set.Manifest.Entries {
+		value := map[string]string{}
+		if err := set.Load(entry, &value); err != nil || value["title"] != kinds[entry.Kind] {
+			t.Errorf("Load of %s returned %#v, %v", entry.File, value, err)
+		}
+	}
+}
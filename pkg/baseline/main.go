@@ -0,0 +1,90 @@
+package baseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Entry is dashboard state on one server after last sync.
+type Entry struct {
+	Hash    string `json:"hash"`
+	Version int    `json:"version"`
+}
+
+// Record is dashboard state on both servers after last sync.
+type Record struct {
+	Left  Entry `json:"left"`
+	Right Entry `json:"right"`
+}
+
+// State is content of local state file.
+// Records are grouped by server pair and dashboard uid.
+type State struct {
+	path  string
+	Pairs map[string]map[string]Record `json:"pairs"`
+}
+
+// DefaultPath returns location of state file in home directory.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error in UserHomeDir: %w", err)
+	}
+	return filepath.Join(home, ".grafana-dashboard-sync-state.json"), nil
+}
+
+// Read loads state from path. Missing file is treated as empty state.
+func Read(path string) (*State, error) {
+	state := &State{path: path, Pairs: map[string]map[string]Record{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error in parsing %s: %w", path, err)
+	}
+	if state.Pairs == nil {
+		state.Pairs = map[string]map[string]Record{}
+	}
+	return state, nil
+}
+
+// Write saves state back to file it was read from.
+func (state *State) Write() error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(state.path, data, 0o600)
+}
+
+func pairKey(left, right string) string {
+	return left + "/" + right
+}
+
+// Get returns record for dashboard uid between left and right server.
+func (state *State) Get(left, right, uid string) (Record, bool) {
+	record, ok := state.Pairs[pairKey(left, right)][uid]
+	return record, ok
+}
+
+// Set stores record for dashboard uid between left and right server.
+func (state *State) Set(left, right, uid string, record Record) {
+	key := pairKey(left, right)
+	if _, ok := state.Pairs[key]; !ok {
+		state.Pairs[key] = map[string]Record{}
+	}
+	state.Pairs[key][uid] = record
+}
+
+// Delete removes dashboard uid from records between left and right server.
+func (state *State) Delete(left, right, uid string) {
+	delete(state.Pairs[pairKey(left, right)], uid)
+}
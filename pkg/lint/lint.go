@@ -0,0 +1,292 @@
+// Package lint checks dashboards against configurable rules.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+// Severities of rules. Rule with severity Off isn't run.
+const (
+	Error   = "error"
+	Warning = "warning"
+	Off     = "off"
+)
+
+// DefaultSchemaVersion is oldest dashboard schema version, which isn't reported as outdated.
+const DefaultSchemaVersion = 39
+
+// Finding is problem found from dashboard. Panel is empty for dashboard level findings.
+type Finding struct {
+	Dashboard string `json:"dashboard"`
+	UID       string `json:"uid"`
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"`
+	Panel     string `json:"panel,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Options configure rules. Severities override default severity of rules by name.
+type Options struct {
+	SchemaVersion int
+	Severities    map[string]string
+}
+
+// Rule checks one thing from dashboard. Check returns findings with panel and message.
+type Rule struct {
+	Name     string
+	Severity string
+	Summary  string
+	check    func(dashboard *api.DashboardJSON, opts Options) []Finding
+}
+
+// Rules are all known rules with their default severity.
+var Rules = []Rule{
+	{Name: "panel-title", Severity: Error, Summary: "panels without title", check: untitledPanels},
+	{Name: "duplicate-title", Severity: Error, Summary: "panels with same title", check: duplicateTitles},
+	{Name: "hardcoded-datasource", Severity: Warning, Summary: "data source uids instead of variables",
+		check: hardcodedDataSources},
+	{Name: "unused-variable", Severity: Warning, Summary: "variables not referenced anywhere", check: unusedVariables},
+	{Name: "undefined-variable", Severity: Error, Summary: "references to variables, which aren't defined",
+		check: undefinedVariables},
+	{Name: "missing-description", Severity: Warning, Summary: "dashboards and panels without description",
+		check: missingDescriptions},
+	{Name: "overlapping-panels", Severity: Warning, Summary: "panels overlapping in grid", check: overlappingPanels},
+	{Name: "schema-version", Severity: Warning, Summary: "outdated schema version", check: outdatedSchema},
+}
+
+// Validate checks that options refer to known rules and severities.
+func (opts Options) Validate() error {
+	for name, severity := range opts.Severities {
+		known := false
+		for _, rule := range Rules {
+			known = known || rule.Name == name
+		}
+		if !known {
+			return fmt.Errorf("unknown lint rule (%s)", name)
+		}
+		if severity != Error && severity != Warning && severity != Off {
+			return fmt.Errorf("unknown severity (%s) for lint rule %s, use error, warning or off", severity, name)
+		}
+	}
+	return nil
+}
+
+// severity returns configured severity of rule.
+func (opts Options) severity(rule Rule) string {
+	if severity, ok := opts.Severities[rule.Name]; ok {
+		return severity
+	}
+	return rule.Severity
+}
+
+// Lint runs rules, which aren't turned off, over dashboard.
+func Lint(dashboard api.DashboardJSON, opts Options) []Finding {
+	if opts.SchemaVersion == 0 {
+		opts.SchemaVersion = DefaultSchemaVersion
+	}
+	findings := []Finding{}
+	for _, rule := range Rules {
+		severity := opts.severity(rule)
+		if severity == Off {
+			continue
+		}
+		for _, finding := range rule.check(&dashboard, opts) {
+			finding.Dashboard = dashboard.Dashboard.Title
+			finding.UID = dashboard.Dashboard.UID
+			finding.Rule = rule.Name
+			finding.Severity = severity
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// panelName identifies panel in findings. Untitled panels are named by id.
+func panelName(panel api.Panel) string {
+	if title := strings.TrimSpace(panel.Title); title != "" {
+		return title
+	}
+	return fmt.Sprintf("#%d", panel.Id)
+}
+
+func untitledPanels(dashboard *api.DashboardJSON, _ Options) []Finding {
+	findings := []Finding{}
+	for _, panel := range dashboard.Flatten() {
+		if strings.TrimSpace(panel.Title) == "" {
+			findings = append(findings, Finding{Panel: panelName(panel), Message: "panel has no title"})
+		}
+	}
+	return findings
+}
+
+func duplicateTitles(dashboard *api.DashboardJSON, _ Options) []Finding {
+	counts := map[string]int{}
+	order := []string{}
+	for _, panel := range dashboard.Flatten() {
+		title := strings.TrimSpace(panel.Title)
+		if title == "" {
+			continue
+		}
+		if counts[title] == 0 {
+			order = append(order, title)
+		}
+		counts[title]++
+	}
+	findings := []Finding{}
+	for _, title := range order {
+		if counts[title] > 1 {
+			findings = append(findings, Finding{
+				Panel: title, Message: fmt.Sprintf("title is used by %d panels", counts[title]),
+			})
+		}
+	}
+	return findings
+}
+
+// hardcoded returns uid or name of data source, unless it is variable or builtin.
+func hardcoded(ds interface{}) string {
+	value := ""
+	switch item := ds.(type) {
+	case string:
+		value = item
+	case map[string]interface{}:
+		value, _ = item["uid"].(string)
+	case api.DashDataSource:
+		value = item.UID
+	}
+	if value == "" || strings.HasPrefix(value, "$") || api.IsBuiltinDataSource(value) {
+		return ""
+	}
+	return value
+}
+
+func hardcodedDataSources(dashboard *api.DashboardJSON, _ Options) []Finding {
+	findings := []Finding{}
+	for _, panel := range dashboard.Flatten() {
+		uids := map[string]bool{}
+		if uid := hardcoded(panel.DataSource); uid != "" {
+			uids[uid] = true
+		}
+		for _, target := range panel.Targets {
+			if uid := hardcoded(target.DataSource); uid != "" {
+				uids[uid] = true
+			}
+		}
+		for _, uid := range sortedKeys(uids) {
+			findings = append(findings, Finding{
+				Panel: panelName(panel), Message: fmt.Sprintf("data source %s is hardcoded, use variable", uid),
+			})
+		}
+	}
+	return findings
+}
+
+func unusedVariables(dashboard *api.DashboardJSON, _ Options) []Finding {
+	findings := []Finding{}
+	for _, name := range dashboard.UnusedVariables() {
+		findings = append(findings, Finding{Message: fmt.Sprintf("variable %s is not used", name)})
+	}
+	return findings
+}
+
+func undefinedVariables(dashboard *api.DashboardJSON, _ Options) []Finding {
+	locations := map[string][]string{}
+	order := []string{}
+	for _, ref := range dashboard.UndefinedVariables() {
+		if _, ok := locations[ref.Name]; !ok {
+			order = append(order, ref.Name)
+		}
+		if !slices.Contains(locations[ref.Name], ref.Location) {
+			locations[ref.Name] = append(locations[ref.Name], ref.Location)
+		}
+	}
+	findings := []Finding{}
+	for _, name := range order {
+		findings = append(findings, Finding{Message: fmt.Sprintf(
+			"variable %s is not defined, used in %s", name, strings.Join(locations[name], ", "),
+		)})
+	}
+	return findings
+}
+
+func missingDescriptions(dashboard *api.DashboardJSON, _ Options) []Finding {
+	findings := []Finding{}
+	if strings.TrimSpace(dashboard.Dashboard.Description) == "" {
+		findings = append(findings, Finding{Message: "dashboard has no description"})
+	}
+	for _, panel := range dashboard.Flatten() {
+		if panel.Type == "row" {
+			continue
+		}
+		if description, _ := panel.Description.(string); strings.TrimSpace(description) == "" {
+			findings = append(findings, Finding{Panel: panelName(panel), Message: "panel has no description"})
+		}
+	}
+	return findings
+}
+
+type gridPos struct {
+	x, y, w, h float64
+}
+
+func parseGridPos(value interface{}) (gridPos, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return gridPos{}, false
+	}
+	x, _ := m["x"].(float64)
+	y, _ := m["y"].(float64)
+	w, _ := m["w"].(float64)
+	h, _ := m["h"].(float64)
+	return gridPos{x: x, y: y, w: w, h: h}, w > 0 && h > 0
+}
+
+func (pos gridPos) overlaps(other gridPos) bool {
+	return pos.x < other.x+other.w && other.x < pos.x+pos.w && pos.y < other.y+other.h && other.y < pos.y+pos.h
+}
+
+// overlapsIn checks panels on same level. Panels of collapsed rows are checked against each other.
+func overlapsIn(panels []api.Panel) []Finding {
+	findings := []Finding{}
+	for i, one := range panels {
+		posOne, ok := parseGridPos(one.GridPos)
+		for j := i + 1; ok && j < len(panels); j++ {
+			if posTwo, ok := parseGridPos(panels[j].GridPos); ok && posOne.overlaps(posTwo) {
+				findings = append(findings, Finding{
+					Panel: panelName(one), Message: "panel overlaps with " + panelName(panels[j]),
+				})
+			}
+		}
+		if len(one.Panels) > 0 {
+			findings = append(findings, overlapsIn(one.Panels)...)
+		}
+	}
+	return findings
+}
+
+func overlappingPanels(dashboard *api.DashboardJSON, _ Options) []Finding {
+	return overlapsIn(dashboard.Dashboard.Panels)
+}
+
+func outdatedSchema(dashboard *api.DashboardJSON, opts Options) []Finding {
+	if dashboard.Dashboard.SchemaVersion >= opts.SchemaVersion {
+		return nil
+	}
+	return []Finding{{Message: fmt.Sprintf(
+		"schema version %d is older than %d", dashboard.Dashboard.SchemaVersion, opts.SchemaVersion,
+	)}}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package lint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jylitalo/grafana-dashboard-sync/api"
+)
+
+const dashboardJSON = `{"dashboard": {
+	"uid": "abc", "title": "Service", "schemaVersion": 36,
+	"templating": {"list": [
+		{"name": "ds", "type": "datasource"},
+		{"name": "job", "type": "query", "query": "label_values(up{instance=~\"$instance\"}, job)"},
+		{"name": "instance", "type": "query"},
+		{"name": "unused", "type": "custom"},
+		{"name": "filters", "type": "adhoc"}
+	]},
+	"panels": [
+		{"id": 1, "title": "Requests", "description": "rps", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+			"datasource": {"uid": "${ds}"}, "targets": [{"expr": "rate(http{job=\"$job\"}[5m])", "datasource": {"uid": "${ds}"}}]},
+		{"id": 2, "title": "Requests", "description": "errors", "gridPos": {"x": 6, "y": 4, "w": 12, "h": 8},
+			"targets": [{"refId": "B", "expr": "up{env=\"$env\"}", "datasource": {"uid": "P1809F7CD0C75ACF3"}}]},
+		{"id": 3, "title": "", "description": "text", "gridPos": {"x": 0, "y": 20, "w": 24, "h": 2}, "type": "text"}
+	]
+}}`
+
+func TestLint(t *testing.T) {
+	dashboard := api.DashboardJSON{}
+	if err := json.Unmarshal([]byte(dashboardJSON), &dashboard); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rule     string
+		panel    string
+		message  string
+		severity string
+	}{
+		{"panel-title", "#3", "panel has no title", Error},
+		{"duplicate-title", "Requests", "title is used by 2 panels", Error},
+		{"hardcoded-datasource", "Requests", "data source P1809F7CD0C75ACF3 is hardcoded, use variable", Warning},
+		{"unused-variable", "", "variable unused is not used", Warning},
+		{"undefined-variable", "", "variable env is not defined, used in panel Requests target B", Error},
+		{"overlapping-panels", "Requests", "panel overlaps with Requests", Warning},
+		{"schema-version", "", "schema version 36 is older than 39", Warning},
+	}
+	findings := Lint(dashboard, Options{Severities: map[string]string{"missing-description": Off}})
+	if len(findings) != len(tests) {
+		t.Errorf("Lint returned %d findings, expected %d: %v", len(findings), len(tests), findings)
+	}
+	for idx, test := range tests {
+		if idx >= len(findings) {
+			break
+		}
+		found := findings[idx]
+		if found.Rule != test.rule || found.Panel != test.panel || found.Message != test.message ||
+			found.Severity != test.severity || found.UID != "abc" {
+			t.Errorf("finding #%d is %+v, expected %+v", idx, found, test)
+		}
+	}
+	findings = Lint(dashboard, Options{SchemaVersion: 30, Severities: map[string]string{
+		"panel-title": Warning, "duplicate-title": Off, "hardcoded-datasource": Off,
+		"unused-variable": Off, "undefined-variable": Off, "overlapping-panels": Off,
+	}})
+	if len(findings) != 2 || findings[0].Severity != Warning || findings[1].Message != "dashboard has no description" {
+		t.Errorf("Lint with options returned %v", findings)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		severities map[string]string
+		fail       bool
+	}{
+		{severities: nil},
+		{severities: map[string]string{"panel-title": Off, "schema-version": Error}},
+		{severities: map[string]string{"no-such-rule": Off}, fail: true},
+		{severities: map[string]string{"panel-title": "fatal"}, fail: true},
+	}
+	for _, test := range tests {
+		if err := (Options{Severities: test.severities}).Validate(); (err != nil) != test.fail {
+			t.Errorf("Validate(%v) returned %v", test.severities, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"io"
 	"log/slog"
 	"os"
 
@@ -8,20 +9,45 @@ import (
 	"github.com/mattn/go-isatty"
 )
 
-func SetupSlog(debug bool, color bool) *slog.Logger {
+// Options for SetupSlog. Sensitive attributes are always redacted. Format is either "text" (default) or "json".
+// When File is given, logs are written there instead of stderr.
+type Options struct {
+	Debug  bool
+	Color  bool
+	Format string
+	File   string
+	// MaxSize (in megabytes) and MaxBackups control rotation of File.
+	MaxSize    int
+	MaxBackups int
+}
+
+func SetupSlog(opts Options) (*slog.Logger, error) {
 	logLevel := map[bool]slog.Level{
 		true:  slog.LevelDebug,
 		false: slog.LevelInfo,
-	}[debug]
-	w := os.Stderr
+	}[opts.Debug]
+	var w io.Writer = os.Stderr
+	terminal := isatty.IsTerminal(os.Stderr.Fd())
+	if opts.File != "" {
+		file, err := openRotating(opts.File, opts.MaxSize, opts.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		w = file
+		terminal = false
+	}
+	if opts.Format == "json" {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: logLevel, ReplaceAttr: Redact})), nil
+	}
 	return slog.New(tint.NewHandler(w, &tint.Options{
 		Level:   logLevel,
-		NoColor: !isatty.IsTerminal(w.Fd()) || !color,
+		NoColor: !terminal || !opts.Color,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.TimeKey && len(groups) == 0 {
+			// timestamps are needed only, when output goes to file or pipe
+			if a.Key == slog.TimeKey && len(groups) == 0 && terminal {
 				return slog.Attr{}
 			}
-			return a
+			return Redact(groups, a)
 		},
-	}))
+	})), nil
 }
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const masked = "***"
+
+// sensitiveKeys are parts of attribute keys, which values are masked.
+var sensitiveKeys = []string{"bearer", "password", "token", "secret", "securejsondata", "authorization"}
+
+var bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"]+`)
+
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, item := range sensitiveKeys {
+		if strings.Contains(key, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact masks values of sensitive attributes, attributes in sensitive groups
+// and bearer tokens in string values. It can be used as slog.HandlerOptions.ReplaceAttr.
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	for _, group := range slices.Concat(groups, []string{a.Key}) {
+		if sensitive(group) {
+			return slog.String(a.Key, masked)
+		}
+	}
+	if a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, bearerPattern.ReplaceAllString(a.Value.String(), "${1}"+masked))
+	}
+	if err, ok := a.Value.Any().(error); ok {
+		return slog.String(a.Key, bearerPattern.ReplaceAllString(err.Error(), "${1}"+masked))
+	}
+	return a
+}
@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{ReplaceAttr: Redact}))
+	logger.Info("request",
+		"bearer", "glsa_abc",
+		slog.Group("secureJsonData", "httpHeaderValue1", "hunter2"),
+		"header", "Authorization: Bearer glsa_def",
+		"err", errors.New("failed with Bearer glsa_ghi"),
+		"server", "prod",
+	)
+	out := buf.String()
+	for _, secret := range []string{"glsa_abc", "hunter2", "glsa_def", "glsa_ghi"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%s was not redacted from %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, `"server":"prod"`) {
+		t.Errorf("server was redacted from %s", out)
+	}
+}
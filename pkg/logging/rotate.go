@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+const megabyte = 1024 * 1024
+
+// rotatingFile is log file, which is renamed to <path>.1 when it grows over maxSize.
+// Older files are shifted to <path>.2 ... <path>.<maxBackups>.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// openRotating opens log file for appending. Zero maxSize disables rotation.
+func openRotating(path string, maxSize, maxBackups int) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: int64(maxSize) * megabyte, maxBackups: maxBackups}
+	return rf, rf.open()
+}
+
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error in opening log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	if rf.maxBackups < 1 {
+		if err := os.Remove(rf.path); err != nil {
+			return err
+		}
+		return rf.open()
+	}
+	for idx := rf.maxBackups - 1; idx > 0; idx-- {
+		from := fmt.Sprintf("%s.%d", rf.path, idx)
+		if _, err := os.Stat(from); err == nil {
+			if err = os.Rename(from, fmt.Sprintf("%s.%d", rf.path, idx+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+		return err
+	}
+	return rf.open()
+}
+
+func (rf *rotatingFile) Write(data []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(data)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(data)
+	rf.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.log")
+	rf, err := openRotating(path, 1, 2)
+	if err != nil {
+		t.Fatalf("openRotating failed due to %v", err)
+	}
+	line := []byte(strings.Repeat("x", megabyte/2-1) + "\n")
+	for idx := 0; idx < 7; idx++ {
+		if _, err = rf.Write(line); err != nil {
+			t.Fatalf("Write failed due to %v", err)
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Errorf("%s missing: %v", name, err)
+		} else if info.Size() > megabyte {
+			t.Errorf("%s is too big (%d)", name, info.Size())
+		}
+	}
+	if _, err = os.Stat(path + ".3"); err == nil {
+		t.Errorf("only 2 backups should be kept")
+	}
+}